@@ -12,6 +12,7 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/holden/vga-go/internal/display"
 	"github.com/holden/vga-go/internal/effects"
 	"github.com/holden/vga-go/internal/music"
 	demosync "github.com/holden/vga-go/internal/sync"
@@ -24,6 +25,16 @@ var version = "dev"
 
 const Scale = 3
 
+// windowScale picks an integer window scale so larger modes open at roughly
+// the same size as 320-wide modes at Scale.
+func windowScale(mode vga.Mode) int {
+	s := Scale * 320 / mode.Width
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
 type Demo struct {
 	fb           *vga.Framebuffer
 	screen       *ebiten.Image
@@ -36,13 +47,15 @@ type Demo struct {
 	quit         chan struct{} // closed by signal handler
 	shuttingDown bool
 	fadeStart    time.Time
+	post         *display.Presenter // nil when no post-processing is enabled
+	integerScale bool
 }
 
 var debugMode bool
 
-func NewDemo(modFile, cueFile string) (*Demo, error) {
-	fb := vga.NewFramebuffer(vga.DefaultPalette())
-
+// buildSequencer creates the demo's effects and timeline (from cueFile, or
+// the default timeline) and initialises the first effect on fb.
+func buildSequencer(fb *vga.Framebuffer, cueFile string) (*demosync.Sequencer, error) {
 	// Create effects
 	plasma := effects.NewPlasma()
 	fire := effects.NewFire()
@@ -50,7 +63,32 @@ func NewDemo(modFile, cueFile string) (*Demo, error) {
 	starfield := effects.NewStarfield()
 	sineScroller := effects.NewSineScroller("HELLO DEMOSCENE! THIS IS VGA-GO - A DEMO ENGINE IN GO!    ")
 	bigScroller := effects.NewBigScroller("VGA-GO DEMO ENGINE    ")
-	efx := []effects.Effect{plasma, fire, tunnel, starfield, sineScroller, bigScroller}
+	rotozoom := effects.NewRotozoom()
+	vector := effects.NewVector()
+	voxel := effects.NewVoxel()
+	metaballs := effects.NewMetaballs()
+	rasterBars := effects.NewRasterBars()
+	twister := effects.NewTwister()
+	// Starfield only uses a few low palette entries, so bars and twister layer cleanly over it.
+	starBars := effects.NewLayers(effects.NewStarfield(), effects.NewRasterBars(), effects.NewTwister())
+	bump := effects.NewBump()
+	picture := effects.NewPicture()
+	water := effects.NewWater()
+	plasmaWater := effects.NewFilterChain(effects.NewPlasma(), effects.NewWater())
+	shadebobs := effects.NewShadebobs()
+	floor := effects.NewFloor()
+	fractal := effects.NewFractal()
+	dotBall := effects.NewDotBall()
+	dotTunnel := effects.NewDotTunnel()
+	fireworks := effects.NewFireworks()
+	distort := effects.NewDistort()
+	rotozoomDistort := effects.NewFilterChain(effects.NewRotozoom(), effects.NewDistort())
+	moire := effects.NewMoire()
+	charScroller := effects.NewCharScroller()
+	textWriter := effects.NewTextWriter()
+	efx := []effects.Effect{plasma, fire, tunnel, starfield, sineScroller, bigScroller, rotozoom, vector, voxel, metaballs,
+		rasterBars, twister, starBars, bump, picture, water, plasmaWater, shadebobs, floor, fractal,
+		dotBall, dotTunnel, fireworks, distort, rotozoomDistort, moire, charScroller, textWriter}
 
 	var timeline *demosync.Timeline
 	if cueFile != "" {
@@ -67,18 +105,54 @@ func NewDemo(modFile, cueFile string) (*Demo, error) {
 			{Pos: demosync.Position{Order: 2, Row: 0}, EffectIdx: 2, Transition: "cut"},
 			{Pos: demosync.Position{Order: 3, Row: 0}, EffectIdx: 1, Transition: "cut"},
 			{Pos: demosync.Position{Order: 4, Row: 0}, EffectIdx: 0, Transition: "fade", FadeDur: 2.0},
+			{Pos: demosync.Position{Order: 5, Row: 0}, EffectIdx: 6, Transition: "cut"},
+			{Pos: demosync.Position{Order: 6, Row: 0}, EffectIdx: 7, Transition: "cut"},
+			{Pos: demosync.Position{Order: 7, Row: 0}, EffectIdx: 8, Transition: "cut"},
+			{Pos: demosync.Position{Order: 8, Row: 0}, EffectIdx: 9, Transition: "cut"},
+			{Pos: demosync.Position{Order: 9, Row: 0}, EffectIdx: 12, Transition: "cut"},
+			{Pos: demosync.Position{Order: 10, Row: 0}, EffectIdx: 13, Transition: "cut"},
+			{Pos: demosync.Position{Order: 11, Row: 0}, EffectIdx: 16, Transition: "cut"},
+			{Pos: demosync.Position{Order: 12, Row: 0}, EffectIdx: 17, Transition: "cut"},
+			{Pos: demosync.Position{Order: 13, Row: 0}, EffectIdx: 18, Transition: "cut"},
+			{Pos: demosync.Position{Order: 14, Row: 0}, EffectIdx: 19, Transition: "cut"},
+			{Pos: demosync.Position{Order: 15, Row: 0}, EffectIdx: 20, Transition: "cut"},
+			{Pos: demosync.Position{Order: 16, Row: 0}, EffectIdx: 21, Transition: "cut"},
+			{Pos: demosync.Position{Order: 17, Row: 0}, EffectIdx: 22, Transition: "cut"},
+			{Pos: demosync.Position{Order: 18, Row: 0}, EffectIdx: 24, Transition: "cut"},
+			{Pos: demosync.Position{Order: 19, Row: 0}, EffectIdx: 25, Transition: "cut"},
+			{Pos: demosync.Position{Order: 20, Row: 0}, EffectIdx: 26, Transition: "cut"},
+			{Pos: demosync.Position{Order: 21, Row: 0}, EffectIdx: 27, Transition: "cut"},
 		})
 	}
 
 	seq := demosync.NewSequencer(efx, timeline)
+	if err := seq.CheckParams(); err != nil {
+		return nil, fmt.Errorf("invalid cue params: %w", err)
+	}
 	seq.InitFirst(fb)
+	return seq, nil
+}
+
+func NewDemo(modFile, cueFile string, mode vga.Mode, post display.Options) (*Demo, error) {
+	fb := vga.NewFramebufferMode(mode, vga.DefaultPalette())
+	seq, err := buildSequencer(fb, cueFile)
+	if err != nil {
+		return nil, err
+	}
 
 	d := &Demo{
-		fb:        fb,
-		screen:    ebiten.NewImage(vga.Width, vga.Height),
-		sequencer: seq,
-		lastTime:  time.Now(),
-		quit:      make(chan struct{}),
+		fb:           fb,
+		sequencer:    seq,
+		lastTime:     time.Now(),
+		quit:         make(chan struct{}),
+		integerScale: post.Integer,
+	}
+	if post.Enabled() {
+		d.post = display.NewPresenter(fb.Width, fb.Height, post)
+		w, h := d.post.Size()
+		d.screen = ebiten.NewImage(w, h)
+	} else {
+		d.screen = ebiten.NewImage(fb.Width, fb.Height)
 	}
 
 	// Load and start music if a mod file is provided
@@ -211,8 +285,21 @@ func (d *Demo) updateFade() {
 
 func (d *Demo) Draw(screen *ebiten.Image) {
 	d.sequencer.Draw(d.fb)
-	d.screen.WritePixels(d.fb.RGBA())
-	screen.DrawImage(d.screen, nil)
+	if d.post != nil {
+		d.screen.WritePixels(d.post.Process(d.fb.RGBA()).Pix)
+	} else {
+		d.screen.WritePixels(d.fb.RGBA())
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	if d.integerScale {
+		b := d.screen.Bounds()
+		sb := screen.Bounds()
+		scale, offX, offY := display.Letterbox(b.Dx(), b.Dy(), sb.Dx(), sb.Dy(), true)
+		op.GeoM.Scale(scale, scale)
+		op.GeoM.Translate(offX, offY)
+	}
+	screen.DrawImage(d.screen, op)
 
 	if d.showDebug && d.player != nil {
 		info := d.player.SyncState()
@@ -227,7 +314,12 @@ func (d *Demo) Draw(screen *ebiten.Image) {
 }
 
 func (d *Demo) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return vga.Width, vga.Height
+	if d.integerScale {
+		// Draw letterboxes at whole-number scales itself
+		return outsideWidth, outsideHeight
+	}
+	b := d.screen.Bounds()
+	return b.Dx(), b.Dy()
 }
 
 func (d *Demo) Close() {
@@ -252,13 +344,50 @@ func main() {
 	cueFile := flag.String("cue", "", "Path to JSON cue file (demo timeline)")
 	fullscreen := flag.Bool("fullscreen", false, "Start in fullscreen mode")
 	debug := flag.Bool("debug", false, "Enable debug logging for music playback")
+	modeName := flag.String("mode", vga.DefaultMode.Name, "Video mode: 13h, x, x360, x400, 12h, text80x50")
+	crt := flag.Bool("crt", false, "Enable all CRT filters (aspect, scanlines, mask, bloom, barrel)")
+	aspect := flag.Bool("aspect", false, "Correct to 4:3 aspect ratio")
+	integer := flag.Bool("integer", false, "Letterbox the window at integer scales")
+	scanlines := flag.Float64("scanlines", 0, "Scanline darkness (0-1)")
+	mask := flag.Float64("mask", 0, "RGB shadow-mask strength (0-1)")
+	bloom := flag.Float64("bloom", 0, "Bloom amount (0-1)")
+	barrel := flag.Float64("barrel", 0, "Barrel distortion amount (e.g. 0.08)")
+	renderDir := flag.String("render", "", "Render frames offline as PNGs into this directory instead of opening a window")
+	frames := flag.Int("frames", 600, "Number of frames to render with -render")
+	fps := flag.Float64("fps", 60, "Frame rate for -render")
 	flag.Parse()
 
 	debugMode = *debug
 
+	mode, ok := vga.ModeByName(*modeName)
+	if !ok {
+		log.Fatalf("unknown video mode: %s", *modeName)
+	}
+
+	scale := windowScale(mode)
+	post := display.Options{
+		Scale:     scale,
+		Aspect:    *aspect,
+		Scanlines: *scanlines,
+		Mask:      *mask,
+		Bloom:     *bloom,
+		Barrel:    *barrel,
+	}
+	if *crt {
+		post = display.CRT(scale)
+	}
+	post.Integer = *integer
+
 	log.Printf("VGA-GO Demo Engine %s", version)
 
-	demo, err := NewDemo(*modFile, *cueFile)
+	if *renderDir != "" {
+		if err := renderOffline(*renderDir, *frames, *fps, *modFile, *cueFile, mode, post); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	demo, err := NewDemo(*modFile, *cueFile, mode, post)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -273,7 +402,12 @@ func main() {
 		close(demo.quit)
 	}()
 
-	ebiten.SetWindowSize(vga.Width*Scale, vga.Height*Scale)
+	w, h := mode.Width*scale, mode.Height*scale
+	if demo.post != nil {
+		// The presenter's frames are already scaled, and 4:3 with -aspect
+		w, h = demo.post.Size()
+	}
+	ebiten.SetWindowSize(w, h)
 	ebiten.SetWindowTitle("VGA-GO Demo")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetFullscreen(*fullscreen)
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/holden/vga-go/internal/display"
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// renderOffline renders the demo to numbered PNG files in dir without opening
+// a window or an audio device. Frames go through the same display pipeline
+// as the window. Music sync is driven by stepping libxmp directly to each
+// frame's timestamp, so a render does not depend on wall-clock timing.
+func renderOffline(dir string, frames int, fps float64, modFile, cueFile string, mode vga.Mode, post display.Options) error {
+	if fps <= 0 {
+		return fmt.Errorf("invalid frame rate: %v", fps)
+	}
+	fb := vga.NewFramebufferMode(mode, vga.DefaultPalette())
+	seq, err := buildSequencer(fb, cueFile)
+	if err != nil {
+		return err
+	}
+
+	var pres *display.Presenter
+	if post.Enabled() {
+		pres = display.NewPresenter(fb.Width, fb.Height, post)
+	}
+
+	var xmp *music.Context
+	if modFile != "" {
+		xmp = music.NewContext()
+		defer xmp.Close()
+		if err := xmp.LoadModule(modFile); err != nil {
+			return fmt.Errorf("failed to load module %s: %w", modFile, err)
+		}
+		defer xmp.ReleaseModule()
+		if err := xmp.StartPlayer(music.SampleRate); err != nil {
+			return err
+		}
+		defer xmp.EndPlayer()
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create render directory: %w", err)
+	}
+
+	dt := 1.0 / fps
+	var info music.FrameInfo
+	songEnded := false
+	for f := 0; f < frames; f++ {
+		// Advance the module until it catches up with this frame's time
+		frameMs := float64(f) * dt * 1000
+		for xmp != nil && !songEnded && float64(info.TimeMs) < frameMs {
+			if !xmp.PlayFrame() {
+				songEnded = true
+				break
+			}
+			info = xmp.GetFrameInfo()
+		}
+
+		seq.Update(dt, info, fb)
+		seq.Draw(fb)
+
+		var img image.Image
+		if pres != nil {
+			img = pres.Process(fb.RGBA())
+		} else {
+			img = &image.RGBA{Pix: fb.RGBA(), Stride: fb.Width * 4, Rect: image.Rect(0, 0, fb.Width, fb.Height)}
+		}
+		if err := writePNG(filepath.Join(dir, fmt.Sprintf("frame%05d.png", f)), img); err != nil {
+			return err
+		}
+		if debugMode && f%max(1, int(fps)) == 0 {
+			log.Printf("[render] frame %d/%d ord=%d row=%d", f, frames, info.Order, info.Row)
+		}
+	}
+	log.Printf("[render] wrote %d frames to %s", frames, dir)
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,234 @@
+// Package display implements the presentation stage: turning the RGBA frame
+// produced by vga.Framebuffer into what the viewer sees, with optional CRT
+// post-processing. It is pure Go on image.RGBA so the same filters apply in
+// the window and in offline frame renders.
+package display
+
+import (
+	"image"
+	"math"
+)
+
+// Options selects the post-processing applied to each frame.
+type Options struct {
+	Scale     int     // integer upscale factor before filtering (minimum 1)
+	Aspect    bool    // stretch to 4:3 (VGA modes like 320x200 have non-square pixels)
+	Scanlines float64 // 0-1: how much to darken the gap between source lines
+	Mask      float64 // 0-1: strength of the RGB shadow-mask pattern
+	Bloom     float64 // 0-1: amount of blurred highlight glow added back
+	Barrel    float64 // barrel distortion amount (around 0.05-0.2 looks like a CRT)
+
+	// Integer letterboxes the window at whole-number scales. It only affects
+	// on-screen presentation (see Letterbox), not the processed frame.
+	Integer bool
+}
+
+// Enabled reports whether any filter needs the CPU pipeline; if not, the
+// frame can be shown as-is and scaled by the GPU.
+func (o Options) Enabled() bool {
+	return o.Aspect || o.Scanlines > 0 || o.Mask > 0 || o.Bloom > 0 || o.Barrel != 0
+}
+
+// CRT is a preset combining every filter at moderate strength.
+func CRT(scale int) Options {
+	return Options{Scale: scale, Aspect: true, Scanlines: 0.35, Mask: 0.25, Bloom: 0.3, Barrel: 0.08}
+}
+
+// Presenter applies Options to frames of a fixed source size. The resampling
+// and distortion mapping is computed once, so per-frame work is a table walk.
+type Presenter struct {
+	opts       Options
+	srcW, srcH int
+	out        *image.RGBA
+
+	srcIdx []int32 // per output pixel: source pixel index, -1 for black
+	shade  []uint8 // per output pixel: scanline brightness (255 = full)
+	bloom  []uint16
+	tmp    []uint16
+}
+
+// NewPresenter creates a presenter for srcW x srcH frames.
+func NewPresenter(srcW, srcH int, opts Options) *Presenter {
+	if opts.Scale < 1 {
+		opts.Scale = 1
+	}
+	outW := srcW * opts.Scale
+	outH := srcH * opts.Scale
+	if opts.Aspect {
+		outH = outW * 3 / 4
+	}
+	p := &Presenter{
+		opts: opts,
+		srcW: srcW,
+		srcH: srcH,
+		out:  image.NewRGBA(image.Rect(0, 0, outW, outH)),
+	}
+	p.buildMap()
+	if opts.Bloom > 0 {
+		p.bloom = make([]uint16, srcW*srcH*3)
+		p.tmp = make([]uint16, srcW*srcH*3)
+	}
+	return p
+}
+
+// Size returns the output frame size.
+func (p *Presenter) Size() (int, int) {
+	b := p.out.Bounds()
+	return b.Dx(), b.Dy()
+}
+
+// buildMap precomputes, for every output pixel, which source pixel it shows
+// (after barrel distortion) and how far it sits from the source line centre.
+func (p *Presenter) buildMap() {
+	outW, outH := p.Size()
+	p.srcIdx = make([]int32, outW*outH)
+	p.shade = make([]uint8, outW*outH)
+	k := p.opts.Barrel
+	for oy := 0; oy < outH; oy++ {
+		for ox := 0; ox < outW; ox++ {
+			u := (float64(ox) + 0.5) / float64(outW)
+			v := (float64(oy) + 0.5) / float64(outH)
+			if k != 0 {
+				nx, ny := u*2-1, v*2-1
+				f := 1 + k*(nx*nx+ny*ny)
+				u = (nx*f + 1) / 2
+				v = (ny*f + 1) / 2
+			}
+			i := oy*outW + ox
+			if u < 0 || u >= 1 || v < 0 || v >= 1 {
+				p.srcIdx[i] = -1
+				continue
+			}
+			sy := v * float64(p.srcH)
+			sx := int(u * float64(p.srcW))
+			p.srcIdx[i] = int32(int(sy)*p.srcW + sx)
+
+			// Darken towards the bottom edge of each source line.
+			shade := 1.0
+			if p.opts.Scanlines > 0 {
+				frac := sy - math.Floor(sy)
+				shade = 1 - p.opts.Scanlines*math.Max(0, frac*2-1)
+			}
+			p.shade[i] = uint8(shade * 255)
+		}
+	}
+}
+
+// Process filters one RGBA frame (srcW*srcH*4 bytes, as from
+// vga.Framebuffer.RGBA) and returns the output image. The image is reused
+// between calls.
+func (p *Presenter) Process(src []byte) *image.RGBA {
+	if p.bloom != nil {
+		p.computeBloom(src)
+	}
+	outW, _ := p.Size()
+	dst := p.out.Pix
+	bloomAmt := int(p.opts.Bloom * 256)
+	maskLo := int((1 - p.opts.Mask) * 256)
+
+	for i, si := range p.srcIdx {
+		o := i * 4
+		if si < 0 {
+			dst[o], dst[o+1], dst[o+2], dst[o+3] = 0, 0, 0, 255
+			continue
+		}
+		s := int(si) * 4
+		r, g, b := int(src[s]), int(src[s+1]), int(src[s+2])
+		if p.bloom != nil {
+			bi := int(si) * 3
+			r += int(p.bloom[bi]) * bloomAmt >> 8
+			g += int(p.bloom[bi+1]) * bloomAmt >> 8
+			b += int(p.bloom[bi+2]) * bloomAmt >> 8
+		}
+		sh := int(p.shade[i])
+		r, g, b = r*sh>>8, g*sh>>8, b*sh>>8
+		if p.opts.Mask > 0 {
+			// Aperture-grille triads: each column favours one phosphor.
+			switch (i % outW) % 3 {
+			case 0:
+				g, b = g*maskLo>>8, b*maskLo>>8
+			case 1:
+				r, b = r*maskLo>>8, b*maskLo>>8
+			case 2:
+				r, g = r*maskLo>>8, g*maskLo>>8
+			}
+		}
+		dst[o] = clamp8(r)
+		dst[o+1] = clamp8(g)
+		dst[o+2] = clamp8(b)
+		dst[o+3] = 255
+	}
+	return p.out
+}
+
+// computeBloom fills p.bloom with a blurred bright pass of the source frame,
+// at source resolution to keep it cheap.
+func (p *Presenter) computeBloom(src []byte) {
+	n := p.srcW * p.srcH
+	for i := 0; i < n; i++ {
+		for c := 0; c < 3; c++ {
+			v := int(src[i*4+c])
+			p.bloom[i*3+c] = uint16(v * v / 255) // bright pass: emphasise highlights
+		}
+	}
+	const radius = 3
+	boxBlur(p.tmp, p.bloom, p.srcW, p.srcH, radius, 1, p.srcW)
+	boxBlur(p.bloom, p.tmp, p.srcH, p.srcW, radius, p.srcW, 1)
+}
+
+// boxBlur blurs lines of length n (stepping by step pixels) for count lines
+// (each starting lineStep pixels apart), reading src and writing dst.
+func boxBlur(dst, src []uint16, n, count, radius, step, lineStep int) {
+	width := 2*radius + 1
+	for l := 0; l < count; l++ {
+		base := l * lineStep
+		for c := 0; c < 3; c++ {
+			sum := 0
+			for k := -radius; k <= radius; k++ {
+				sum += int(src[(base+clampInt(k, 0, n-1)*step)*3+c])
+			}
+			for i := 0; i < n; i++ {
+				dst[(base+i*step)*3+c] = uint16(sum / width)
+				out := clampInt(i-radius, 0, n-1)
+				in := clampInt(i+radius+1, 0, n-1)
+				sum += int(src[(base+in*step)*3+c]) - int(src[(base+out*step)*3+c])
+			}
+		}
+	}
+}
+
+func clamp8(v int) uint8 {
+	if v > 255 {
+		return 255
+	}
+	if v < 0 {
+		return 0
+	}
+	return uint8(v)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Letterbox fits a w x h frame inside a dstW x dstH area, centred. With
+// integer set, the scale is rounded down to a whole number (at least 1) so
+// every source pixel covers the same number of screen pixels.
+func Letterbox(w, h, dstW, dstH int, integer bool) (scale, offX, offY float64) {
+	if w <= 0 || h <= 0 {
+		return 1, 0, 0
+	}
+	scale = math.Min(float64(dstW)/float64(w), float64(dstH)/float64(h))
+	if integer {
+		scale = math.Max(1, math.Floor(scale))
+	}
+	offX = math.Floor((float64(dstW) - float64(w)*scale) / 2)
+	offY = math.Floor((float64(dstH) - float64(h)*scale) / 2)
+	return scale, offX, offY
+}
@@ -0,0 +1,114 @@
+package display
+
+import "testing"
+
+func TestPresenterSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		opts         Options
+		wantW, wantH int
+	}{
+		{"unscaled", 320, 200, Options{Scale: 1}, 320, 200},
+		{"scale below 1", 320, 200, Options{}, 320, 200},
+		{"scaled", 320, 200, Options{Scale: 3}, 960, 600},
+		{"aspect", 320, 200, Options{Scale: 2, Aspect: true}, 640, 480},
+		{"aspect, already 4:3", 320, 240, Options{Scale: 2, Aspect: true}, 640, 480},
+		{"aspect, 360 wide", 360, 240, Options{Scale: 2, Aspect: true}, 720, 540},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := NewPresenter(tt.w, tt.h, tt.opts).Size()
+			if w != tt.wantW || h != tt.wantH {
+				t.Errorf("Size = %dx%d, want %dx%d", w, h, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestLetterbox(t *testing.T) {
+	tests := []struct {
+		name              string
+		w, h, dstW, dstH  int
+		integer           bool
+		scale, offX, offY float64
+	}{
+		{"exact fit", 320, 200, 640, 400, false, 2, 0, 0},
+		{"pillarbox", 320, 200, 1000, 400, false, 2, 180, 0},
+		{"letterbox", 320, 200, 640, 600, false, 2, 0, 100},
+		{"fractional", 320, 200, 480, 300, false, 1.5, 0, 0},
+		{"integer rounds down", 320, 200, 700, 500, true, 2, 30, 50},
+		{"integer at least 1", 320, 200, 100, 100, true, 1, -110, -50},
+		{"empty source", 0, 200, 640, 400, false, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scale, offX, offY := Letterbox(tt.w, tt.h, tt.dstW, tt.dstH, tt.integer)
+			if scale != tt.scale || offX != tt.offX || offY != tt.offY {
+				t.Errorf("Letterbox = %v, %v, %v, want %v, %v, %v", scale, offX, offY, tt.scale, tt.offX, tt.offY)
+			}
+		})
+	}
+}
+
+func TestPresenterMap(t *testing.T) {
+	const w, h = 320, 200
+	tests := []struct {
+		name   string
+		opts   Options
+		ox, oy int
+		src    int32 // source pixel index, -1 for black
+		shade  uint8
+	}{
+		{"top left", Options{Scale: 2}, 0, 0, 0, 255},
+		{"scaled pixel", Options{Scale: 2}, 3, 1, 1, 255},
+		{"next source line", Options{Scale: 2}, 0, 2, w, 255},
+		{"bottom right", Options{Scale: 2}, 639, 399, w*h - 1, 255},
+		{"aspect stretches lines", Options{Aspect: true}, 0, 239, (h - 1) * w, 255},
+		{"barrel blanks the corner", Options{Scale: 2, Barrel: 0.2}, 0, 0, -1, 0},
+		{"barrel keeps the centre", Options{Scale: 2, Barrel: 0.2}, 320, 200, h/2*w + w/2, 255},
+		{"barrel pulls edges in", Options{Scale: 2, Barrel: 0.2}, 320, 0, -1, 0},
+		{"scanline top", Options{Scale: 4, Scanlines: 0.5}, 0, 0, 0, 255},
+		{"scanline middle", Options{Scale: 4, Scanlines: 0.5}, 0, 2, 0, 223}, // 1 - 0.5 * 0.25
+		{"scanline bottom", Options{Scale: 4, Scanlines: 0.5}, 0, 3, 0, 159}, // 1 - 0.5 * 0.75
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPresenter(w, h, tt.opts)
+			outW, _ := p.Size()
+			i := tt.oy*outW + tt.ox
+			if p.srcIdx[i] != tt.src {
+				t.Errorf("source index %d, want %d", p.srcIdx[i], tt.src)
+			}
+			if tt.src >= 0 && p.shade[i] != tt.shade {
+				t.Errorf("shade %d, want %d", p.shade[i], tt.shade)
+			}
+		})
+	}
+}
+
+// Process applies the map: black outside the barrel, scanline shading
+// inside.
+func TestPresenterProcess(t *testing.T) {
+	const w, h = 4, 4
+	src := make([]byte, w*h*4)
+	for i := range src {
+		src[i] = 255
+	}
+	out := NewPresenter(w, h, Options{Scale: 4, Barrel: 0.2}).Process(src)
+	if c := out.RGBAAt(0, 0); c.R != 0 || c.A != 255 {
+		t.Errorf("corner = %v, want opaque black", c)
+	}
+	out = NewPresenter(w, h, Options{Scale: 4, Scanlines: 0.5}).Process(src)
+	for _, tt := range []struct {
+		y    int
+		want uint8
+	}{
+		{4, 254}, // 255 * 255 >> 8
+		{7, 158}, // 255 * 159 >> 8
+	} {
+		if c := out.RGBAAt(0, tt.y); c.R != tt.want || c.G != tt.want || c.B != tt.want {
+			t.Errorf("row %d = %v, want grey %d", tt.y, c, tt.want)
+		}
+	}
+}
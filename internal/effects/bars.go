@@ -0,0 +1,322 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// barShades is the length of each colour ramp used by the bar effects.
+const barShades = 16
+
+// barRamps builds barShades entries per colour, dark to the colour to a
+// white highlight.
+func barRamps(colors []color.RGBA) []color.RGBA {
+	out := make([]color.RGBA, 0, len(colors)*barShades)
+	for _, c := range colors {
+		dark := color.RGBA{c.R / 8, c.G / 8, c.B / 8, 255}
+		r := vga.RampPalette(dark, c, color.RGBA{255, 255, 255, 255})
+		for i := 0; i < barShades; i++ {
+			// Stop short of pure white so the highlight keeps a tint.
+			out = append(out, r[i*220/(barShades-1)])
+		}
+	}
+	return out
+}
+
+// barColors reads the "colors" list (at most limit) and the palette range
+// they occupy from "palette_base", shades entries per colour.
+func barColors(p Params, def []color.RGBA, limit, defBase, shades int) ([]color.RGBA, int, error) {
+	colors := slices.Clone(def)
+	if list, ok := p["colors"].([]any); ok && len(list) > 0 {
+		colors = colors[:0]
+		for i, item := range list {
+			if i == limit {
+				break
+			}
+			s, _ := item.(string)
+			c, err := parseColor(s)
+			if err != nil {
+				return nil, 0, fmt.Errorf("colors: %w", err)
+			}
+			colors = append(colors, c)
+		}
+	}
+	base := p.Int("palette_base", defBase)
+	if n := max(len(colors)*shades, 1); base < 1 || base+n > 256 {
+		return nil, 0, fmt.Errorf("palette_base: %d colours need entries %d-%d, within 1-255",
+			len(colors), base, base+n-1)
+	}
+	return colors, base, nil
+}
+
+// setRange writes entries into fb's palette from index base.
+func setRange(fb *vga.Framebuffer, base int, entries []color.RGBA) {
+	for i, c := range entries {
+		fb.SetPaletteColor(byte(base+i), c)
+	}
+}
+
+// blackPalette is an all-black palette (with opaque alpha).
+func blackPalette() vga.Palette {
+	var pal vga.Palette
+	for i := range pal {
+		pal[i] = color.RGBA{0, 0, 0, 255}
+	}
+	return pal
+}
+
+// RasterBars draws horizontal copper bars swinging on sine paths and
+// sorted by depth. Like the real thing, every bar line is drawn in one
+// palette entry and a copper list rewrites that entry's colour on each
+// scanline, so the shading is exact rather than a palette ramp; the copper
+// also paints colour 0 with a vertical background gradient. The swing
+// widens on the beat and the bars thicken on each row. Colour 0 is left
+// transparent, so they can be layered over another effect (see Layers);
+// the background gradient then shows through wherever the effect below is
+// colour 0.
+//
+// Cue params: "bars" (count), "colors" (up to 8 "#rrggbb"), "thickness"
+// (fraction of the screen height), "amplitude", "speed", "background"
+// (two "#rrggbb", top and bottom) and "palette_base" (the entry the copper
+// rewrites, default 112).
+type RasterBars struct {
+	n         int
+	colors    []color.RGBA
+	ramps     []vga.Palette // dark to colour to highlight, per colour
+	base      int
+	thickness float64
+	amplitude float64
+	speed     float64
+	bgTop     color.RGBA
+	bgBottom  color.RGBA
+
+	time   float64
+	beat   float64
+	row    float64
+	order  []barDepth
+	lines  []color.RGBA // bar colour per scanline, set by Draw
+	copper vga.CopperList
+}
+
+type barDepth struct {
+	i int
+	z float64
+}
+
+var defaultBarColors = []color.RGBA{
+	{255, 48, 48, 255}, {255, 160, 32, 255}, {255, 240, 64, 255}, {48, 224, 64, 255}, {48, 160, 255, 255},
+}
+
+func NewRasterBars() *RasterBars {
+	b := &RasterBars{}
+	b.Configure(nil)
+	return b
+}
+
+// Configure applies cue params; see Configurable.
+func (b *RasterBars) Configure(p Params) error {
+	colors, base, err := barColors(p, defaultBarColors, 8, 112, 0)
+	if err != nil {
+		return err
+	}
+	bg := [2]color.RGBA{{0, 0, 24, 255}, {24, 0, 8, 255}}
+	if list, ok := p["background"].([]any); ok {
+		if len(list) != 2 {
+			return fmt.Errorf("background: want two colours, top and bottom")
+		}
+		for i, item := range list {
+			s, _ := item.(string)
+			if bg[i], err = parseColor(s); err != nil {
+				return fmt.Errorf("background: %w", err)
+			}
+		}
+	}
+	b.colors, b.base = colors, base
+	b.bgTop, b.bgBottom = bg[0], bg[1]
+	b.ramps = b.ramps[:0]
+	for _, c := range colors {
+		b.ramps = append(b.ramps, vga.RampPalette(color.RGBA{c.R / 8, c.G / 8, c.B / 8, 255}, c, color.RGBA{255, 255, 255, 255}))
+	}
+	b.n = max(p.Int("bars", 7), 1)
+	b.thickness = p.Float("thickness", 0.07)
+	b.amplitude = p.Float("amplitude", 0.35)
+	b.speed = p.Float("speed", 1)
+	return nil
+}
+
+func (b *RasterBars) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(blackPalette())
+	b.InitOverlay(fb)
+}
+
+// InitOverlay has nothing to set: the copper list colours the bars' entry
+// line by line as the frame is shown. See Overlay.
+func (b *RasterBars) InitOverlay(fb *vga.Framebuffer) {}
+
+func (b *RasterBars) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	b.time += dt * tempo * b.speed
+	b.beat = sync.BeatPulse()
+	b.row = math.Max(b.row*math.Pow(0.001, dt), sync.RowPulse())
+}
+
+func (b *RasterBars) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	h := float64(fb.Height)
+	amp := b.amplitude * h * (1 + 0.25*b.beat)
+	half := math.Max(1, b.thickness*h*(1+0.4*b.row)/2)
+
+	// Bars orbit a horizontal axis; draw the far side first.
+	b.order = b.order[:0]
+	for i := 0; i < b.n; i++ {
+		b.order = append(b.order, barDepth{i, math.Cos(b.time*2 + float64(i)*0.45)})
+	}
+	slices.SortFunc(b.order, func(x, y barDepth) int {
+		switch {
+		case x.z < y.z:
+			return -1
+		case x.z > y.z:
+			return 1
+		}
+		return 0
+	})
+
+	if len(b.lines) != fb.Height {
+		b.lines = make([]color.RGBA, fb.Height)
+	}
+	clear(b.lines) // alpha 0: no bar on the line
+	for _, bd := range b.order {
+		cy := h/2 + amp*math.Sin(b.time*2+float64(bd.i)*0.45)
+		ramp := &b.ramps[bd.i%len(b.colors)]
+		dim := 0.6 + 0.4*(bd.z+1)/2 // far bars are darker
+		for y := max(int(cy-half), 0); y <= min(int(cy+half), fb.Height-1); y++ {
+			d := (float64(y) - cy) / half
+			// Stop short of pure white so the highlight keeps a tint.
+			b.lines[y] = ramp[int(math.Max(1-d*d, 0)*dim*220)]
+		}
+	}
+
+	b.copper.Reset()
+	b.copper.Gradient(0, fb.Height-1, 0, b.bgTop, b.bgBottom)
+	for y, c := range b.lines {
+		if c.A != 0 {
+			fb.HLine(0, fb.Width-1, y, byte(b.base))
+			b.copper.SetColor(y, byte(b.base), c)
+		}
+	}
+	fb.SetCopper(&b.copper)
+}
+
+// Twister is the classic twisting column: a textured square pillar whose
+// rotation angle varies per scanline, each visible face shaded by how
+// squarely it faces the viewer. The twist swings harder on the beat and the
+// column swells on each row. Colour 0 is left transparent, so it can be
+// layered over another effect (see Layers).
+//
+// Cue params: "width" (fraction of the screen width), "twist" (radians of
+// twist along the column), "spin" (radians per second), "colors" (up to 4
+// face colours), "texture" (see Params.Texture; its brightness modulates
+// the faces) and "palette_base" (first palette entry used, default 192).
+type Twister struct {
+	width   float64
+	twist   float64
+	spin    float64
+	colors  []color.RGBA
+	base    int
+	tex     *vga.Sprite
+	time    float64
+	angle   float64
+	beat    float64
+	row     float64
+	scrollV float64
+}
+
+var defaultTwisterColors = []color.RGBA{
+	{255, 96, 32, 255}, {32, 192, 255, 255}, {255, 224, 64, 255}, {160, 64, 255, 255},
+}
+
+func NewTwister() *Twister {
+	t := &Twister{}
+	t.Configure(nil)
+	return t
+}
+
+// Configure applies cue params; see Configurable.
+func (t *Twister) Configure(p Params) error {
+	colors, base, err := barColors(p, defaultTwisterColors, 4, 192, barShades)
+	if err != nil {
+		return err
+	}
+	tex, _, err := p.Texture("texture", "checker")
+	if err != nil {
+		return err
+	}
+	t.colors, t.base, t.tex = colors, base, tex
+	t.width = p.Float("width", 0.3)
+	t.twist = p.Float("twist", 2.5)
+	t.spin = p.Float("spin", 1.2)
+	return nil
+}
+
+func (t *Twister) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(blackPalette())
+	t.InitOverlay(fb)
+}
+
+// InitOverlay sets the twister's palette range only; see Overlay.
+func (t *Twister) InitOverlay(fb *vga.Framebuffer) {
+	setRange(fb, t.base, barRamps(t.colors))
+}
+
+func (t *Twister) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	t.time += dt * tempo
+	t.angle += dt * tempo * t.spin
+	t.scrollV += dt * tempo * 40
+	t.beat = sync.BeatPulse()
+	t.row = math.Max(t.row*math.Pow(0.001, dt), sync.RowPulse())
+}
+
+func (t *Twister) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	w, h := float64(fb.Width), float64(fb.Height)
+	cx := w/2 + w*0.12*math.Sin(t.time*0.7)
+	r := w * t.width / 2 * (1 + 0.12*t.row)
+	twist := t.twist * (1 + 0.5*t.beat)
+	tw, th := t.tex.Width, t.tex.Height
+
+	for y := 0; y < fb.Height; y++ {
+		a := t.angle + twist*math.Sin(float64(y)/h*math.Pi*1.5+t.time)
+		ty := wrapTexel(y+int(t.scrollV), th)
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		for k := 0; k < 4; k++ {
+			a0 := a + float64(k)*math.Pi/2
+			x0 := cx + r*math.Sin(a0)
+			x1 := cx + r*math.Sin(a0+math.Pi/2)
+			if x1 <= x0 {
+				continue // facing away
+			}
+			// Face width relative to the widest it can be: 1 when facing us.
+			light := (x1 - x0) / (r * math.Sqrt2)
+			ramp := t.base + k%len(t.colors)*barShades
+			xs, xe := max(int(math.Ceil(x0)), 0), min(int(x1), fb.Width-1)
+			for x := xs; x <= xe; x++ {
+				u := int((float64(x) - x0) / (x1 - x0) * float64(tw))
+				texel := float64(t.tex.Pixels[ty*tw+min(u, tw-1)]) / 255
+				shade := int(light * (0.55 + 0.45*texel) * (barShades - 1))
+				row[x] = byte(ramp + min(max(shade, 0), barShades-1))
+			}
+		}
+	}
+}
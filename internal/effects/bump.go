@@ -0,0 +1,222 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+const maxLights = 4
+
+// bumpLight is one light: either positioned by the cue (x and y as screen
+// fractions, constant or keyframed) or wandering on its own Lissajous path.
+type bumpLight struct {
+	x, y motion
+	auto bool
+}
+
+// Bump is 2D bump mapping in the Second Reality style: a height map's
+// slopes offset each pixel's lookup into a precomputed light map, lit by
+// moving lights. The light map's brightness maps into a palette ramp that
+// can be limited to the low entries, leaving room for a logo on top.
+//
+// Cue params: "heightmap" (PNG path; otherwise tiling noise from "seed"),
+// "depth" (slope strength), "lights" (a count of wandering lights, or a list
+// of {"x": ..., "y": ...} positions, each a number or keyframes), "palette"
+// and "palette_size" (ramp entries, from index 0).
+type Bump struct {
+	mapKey     string
+	hmW, hmH   int
+	height     []byte
+	depth      float64
+	lights     []bumpLight
+	pal        vga.Palette
+	size       int
+	time, beat float64
+
+	// Rebuilt when the framebuffer size changes.
+	w, h     int
+	slopeX   []int16
+	slopeY   []int16
+	radius   int
+	lightmap []byte
+	pos      [maxLights][2]int
+}
+
+func NewBump() *Bump {
+	b := &Bump{}
+	b.Configure(nil)
+	return b
+}
+
+// Configure applies cue params; see Configurable.
+func (b *Bump) Configure(p Params) error {
+	var lights []bumpLight
+	switch v := p["lights"].(type) {
+	case nil, float64:
+		n := p.Int("lights", 2)
+		if n < 1 || n > maxLights {
+			return fmt.Errorf("lights: must be 1-%d", maxLights)
+		}
+		for i := 0; i < n; i++ {
+			lights = append(lights, bumpLight{auto: true})
+		}
+	case []any:
+		if len(v) < 1 || len(v) > maxLights {
+			return fmt.Errorf("lights: must list 1-%d lights", maxLights)
+		}
+		for i, item := range v {
+			obj, _ := item.(map[string]any)
+			lp := Params(obj)
+			x, err := lp.animated("x", 0.5)
+			if err != nil {
+				return fmt.Errorf("lights[%d]: %w", i, err)
+			}
+			y, err := lp.animated("y", 0.5)
+			if err != nil {
+				return fmt.Errorf("lights[%d]: %w", i, err)
+			}
+			lights = append(lights, bumpLight{x: x, y: y})
+		}
+	default:
+		return fmt.Errorf("lights: must be a count or a list")
+	}
+
+	size := p.Int("palette_size", 256)
+	if size < 2 || size > 256 {
+		return fmt.Errorf("palette_size: must be 2-256")
+	}
+	ramp, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		ramp = vga.RampPalette(
+			color.RGBA{0, 0, 0, 255}, color.RGBA{16, 40, 112, 255},
+			color.RGBA{96, 160, 224, 255}, color.RGBA{255, 255, 255, 255})
+	}
+
+	// Loading or generating the height map is slow, so skip it when a cue
+	// only changes the lights.
+	key := fmt.Sprint(p["heightmap"], p["seed"])
+	if key != b.mapKey {
+		if path := p.String("heightmap", ""); path != "" {
+			s, _, err := vga.LoadSpritePNG(path)
+			if err != nil {
+				return fmt.Errorf("heightmap: %w", err)
+			}
+			b.hmW, b.hmH, b.height = s.Width, s.Height, s.Pixels
+		} else {
+			rng := rand.New(rand.NewSource(int64(p.Int("seed", 1))))
+			b.hmW, b.hmH = 256, 256
+			b.height = diamondSquare(256, 0.5, rng)
+		}
+		b.mapKey = key
+		b.w = 0 // rebuild slopes
+	}
+	// The slopes are scaled by depth.
+	if depth := p.Float("depth", 1); depth != b.depth {
+		b.depth = depth
+		b.w = 0
+	}
+
+	b.pal = blackPalette()
+	for i := 0; i < size; i++ {
+		b.pal[i] = ramp[i*255/(size-1)]
+	}
+	b.lights, b.size = lights, size
+	return nil
+}
+
+func (b *Bump) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(b.pal)
+}
+
+func (b *Bump) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	b.time += dt * tempo
+	b.beat = sync.BeatPulse()
+	for i := range b.lights {
+		if !b.lights[i].auto {
+			b.lights[i].x.update(dt, tempo, sync)
+			b.lights[i].y.update(dt, tempo, sync)
+		}
+	}
+}
+
+// build precomputes per-pixel slopes (the height map tiled over the screen)
+// and the light map for a w x h screen.
+func (b *Bump) build(w, h int) {
+	b.w, b.h = w, h
+	b.slopeX = make([]int16, w*h)
+	b.slopeY = make([]int16, w*h)
+	at := func(x, y int) int { return int(b.height[wrapTexel(y, b.hmH)*b.hmW+wrapTexel(x, b.hmW)]) }
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			b.slopeX[y*w+x] = int16(float64(at(x+1, y)-at(x-1, y)) * b.depth)
+			b.slopeY[y*w+x] = int16(float64(at(x, y+1)-at(x, y-1)) * b.depth)
+		}
+	}
+
+	// Light map: a soft spot of radius 128 pixels at 320 wide.
+	b.radius = max(128*w/320, 1)
+	d := 2 * b.radius
+	b.lightmap = make([]byte, d*d)
+	for y := 0; y < d; y++ {
+		for x := 0; x < d; x++ {
+			dist := math.Hypot(float64(x-b.radius), float64(y-b.radius)) / float64(b.radius)
+			if dist < 1 {
+				f := 1 - dist
+				b.lightmap[y*d+x] = byte(f * f * 200)
+			}
+		}
+	}
+}
+
+func (b *Bump) Draw(fb *vga.Framebuffer) {
+	if b.w != fb.Width || b.h != fb.Height {
+		b.build(fb.Width, fb.Height)
+	}
+	w, h := float64(fb.Width), float64(fb.Height)
+	for i, l := range b.lights {
+		x, y := l.x.value, l.y.value
+		if l.auto {
+			fi := float64(i)
+			x = 0.5 + 0.4*math.Sin(b.time*(0.5+0.17*fi)+fi*2.1)
+			y = 0.5 + 0.4*math.Cos(b.time*(0.41+0.13*fi)+fi*1.3)
+		}
+		b.pos[i] = [2]int{int(x * w), int(y * h)}
+	}
+	DrawParallel(fb, b)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (b *Bump) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	r, d := b.radius, 2*b.radius
+	// Lights flare slightly on the beat.
+	gain := int((1 + 0.3*b.beat) * 256)
+	scale := b.size - 1
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		for x := range row {
+			i := y*b.w + x
+			nx, ny := int(b.slopeX[i]), int(b.slopeY[i])
+			sum := 0
+			for _, p := range b.pos[:len(b.lights)] {
+				lx := x - p[0] + nx + r
+				ly := y - p[1] + ny + r
+				if uint(lx) < uint(d) && uint(ly) < uint(d) {
+					sum += int(b.lightmap[ly*d+lx])
+				}
+			}
+			row[x] = byte(min(sum*gain>>8, 255) * scale / 255)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// charColors colours text one character at a time from a gradient palette:
+// character i gets the entry step*i along it, the whole run sliding by
+// cycle entries per second and bouncing back at the ends.
+type charColors struct {
+	pal   vga.Palette
+	step  float64
+	cycle float64
+	phase float64
+}
+
+// configure reads "palette", "color_step" and "cycle".
+func (c *charColors) configure(p Params) error {
+	src, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		src = vga.RampPalette(
+			color.RGBA{255, 64, 64, 255}, color.RGBA{255, 224, 64, 255}, color.RGBA{64, 255, 96, 255},
+			color.RGBA{64, 192, 255, 255}, color.RGBA{192, 96, 255, 255})
+	}
+	c.pal = src
+	c.pal[0] = color.RGBA{0, 0, 0, 255} // background
+	c.step = p.Float("color_step", 12)
+	c.cycle = p.Float("cycle", 60)
+	return nil
+}
+
+// at returns the palette entry (1-254) for character i.
+func (c *charColors) at(i int) byte {
+	k := int(float64(i)*c.step+c.phase) % 508
+	if k < 0 {
+		k += 508
+	}
+	if k >= 254 {
+		k = 507 - k
+	}
+	return byte(1 + k)
+}
+
+// drawGlyph draws ch with its top-left corner at (x, y), each font pixel
+// an sx x sy block of colour c. Unset pixels are left alone.
+func drawGlyph(fb *vga.Framebuffer, x, y int, ch byte, sx, sy int, c byte) {
+	for fy, bits := range vga.CP437Font[ch] {
+		for fx := 0; bits != 0; fx++ {
+			if bits&1 != 0 {
+				fb.FillRect(x+fx*sx, y+fy*sy, sx, sy, c)
+			}
+			bits >>= 1
+		}
+	}
+}
+
+// glyphScale returns the size of a font pixel for a scale given at 320
+// wide.
+func glyphScale(fb *vga.Framebuffer, scale int) int {
+	return max(int(float64(scale*fb.Width)/320+0.5), 1)
+}
+
+const defaultScrollText = "VGA-GO DEMO ENGINE * GREETINGS TO ALL SCENERS *"
+
+// CharScroller is a family of classic character-based scrollers. Modes:
+//
+//   - "dycp": different Y per character; each whole glyph rides a sine
+//     wave that stays put while the text moves through it
+//   - "dysp": different Y per sprite; groups of characters move as blocks,
+//     each on its own sine path in both directions
+//   - "vertical": lines of text (split on "\n") rolling up the screen,
+//     swaying sideways
+//   - "cylinder": text wrapped round a spinning 3D cylinder, characters
+//     squeezed as they turn away
+//
+// Cue params: "mode", "text", "scale" (font pixel size at 320 wide),
+// "speed" (pixels per second at 320 wide), "amp" (wave height in pixels at
+// 320 wide; it grows on the beat), "waves" (dycp: sine cycles across the
+// screen), "group" (dysp: characters per sprite), "palette", "color_step"
+// (palette entries between neighbouring characters) and "cycle" (entries
+// per second the colours slide).
+type CharScroller struct {
+	mode   string
+	text   string
+	scale  int
+	speed  float64
+	amp    float64
+	waves  float64
+	group  int
+	lines  []string
+	colors charColors
+
+	time float64
+	pos  float64 // pixels scrolled, at 320 wide
+	beat float64
+}
+
+func NewCharScroller() *CharScroller {
+	c := &CharScroller{}
+	c.Configure(nil)
+	return c
+}
+
+// Configure applies cue params; see Configurable.
+func (c *CharScroller) Configure(p Params) error {
+	mode := p.String("mode", "dycp")
+	switch mode {
+	case "dycp", "dysp", "vertical", "cylinder":
+	default:
+		return fmt.Errorf("mode: unknown mode %q", mode)
+	}
+	text := p.String("text", defaultScrollText)
+	if text == "" {
+		return fmt.Errorf("text: must not be empty")
+	}
+	if err := c.colors.configure(p); err != nil {
+		return err
+	}
+
+	c.mode, c.text = mode, text
+	c.lines = strings.Split(c.text, "\n")
+	c.scale = max(p.Int("scale", 2), 1)
+	c.speed = p.Float("speed", 90)
+	c.amp = p.Float("amp", 40)
+	c.waves = p.Float("waves", 1.5)
+	c.group = max(p.Int("group", 3), 1)
+	return nil
+}
+
+func (c *CharScroller) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(c.colors.pal)
+	vga.LoadFontFromPNG("assets/font1.png")
+	c.pos = 0
+}
+
+func (c *CharScroller) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	c.time += dt * tempo
+	c.pos += dt * tempo * c.speed
+	c.colors.phase += dt * tempo * c.colors.cycle
+	c.beat = sync.BeatPulse()
+}
+
+func (c *CharScroller) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	gs := glyphScale(fb, c.scale)
+	cw := 8 * gs
+	k := float64(fb.Width) / 320
+	amp := c.amp * k * (1 + 0.3*c.beat)
+	w, h := float64(fb.Width), float64(fb.Height)
+	top := (fb.Height - cw) / 2
+
+	// Horizontal modes enter from the right edge and leave at the left.
+	total := float64(fb.Width + len(c.text)*cw)
+	x0 := fb.Width - int(math.Mod(c.pos*k, total))
+
+	switch c.mode {
+	case "dycp":
+		for i := 0; i < len(c.text); i++ {
+			x := x0 + i*cw
+			if x <= -cw || x >= fb.Width {
+				continue
+			}
+			y := top + int(amp*math.Sin(float64(x)/w*2*math.Pi*c.waves+c.time*2))
+			drawGlyph(fb, x, y, c.text[i], gs, gs, c.colors.at(i))
+		}
+	case "dysp":
+		for g := 0; g*c.group < len(c.text); g++ {
+			fg := float64(g)
+			gx := x0 + g*c.group*cw + int(amp*0.5*math.Sin(c.time*1.7+fg*0.8))
+			gy := top + int(amp*math.Sin(c.time*2.3+fg*1.1))
+			if gx <= -c.group*cw || gx >= fb.Width {
+				continue
+			}
+			for j := 0; j < c.group && g*c.group+j < len(c.text); j++ {
+				i := g*c.group + j
+				drawGlyph(fb, gx+j*cw, gy, c.text[i], gs, gs, c.colors.at(i))
+			}
+		}
+	case "vertical":
+		lh := cw + 2*gs
+		total := float64(fb.Height + len(c.lines)*lh)
+		y0 := fb.Height - int(math.Mod(c.pos*k, total))
+		i := 0 // running character index, for colours
+		for l, line := range c.lines {
+			y := y0 + l*lh
+			if y > -lh && y < fb.Height {
+				x := (fb.Width-len(line)*cw)/2 + int(amp*0.5*math.Sin(float64(y)/h*2*math.Pi+c.time*2))
+				for j := 0; j < len(line); j++ {
+					drawGlyph(fb, x+j*cw, y, line[j], gs, gs, c.colors.at(i+j))
+				}
+			}
+			i += len(line)
+		}
+	case "cylinder":
+		c.drawCylinder(fb, gs, top+int(amp*0.25*math.Sin(c.time*2)))
+	}
+}
+
+// drawCylinder draws the text round a cylinder seen side on: a
+// character's position along the text is an angle round it, and only the
+// front half is drawn, each font column as wide as its projection.
+func (c *CharScroller) drawCylinder(fb *vga.Framebuffer, gs, y int) {
+	cw := 8 * gs
+	cx := float64(fb.Width) / 2
+	r := float64(fb.Width) * 0.35
+	// The text wraps round once it is longer than the circumference.
+	loop := math.Max(float64(len(c.text)*cw), 2*math.Pi*r)
+	s := math.Mod(c.pos*float64(fb.Width)/320, loop)
+	for i := 0; i < len(c.text); i++ {
+		// Arc length from the front, the text starting at the right edge.
+		u := math.Mod(float64(i*cw)-s+r*math.Pi/2+loop/2, loop) - loop/2
+		if u < -r*math.Pi/2 || u+float64(cw) > r*math.Pi/2 {
+			continue // round the back
+		}
+		col := c.colors.at(i)
+		glyph := vga.CP437Font[c.text[i]]
+		for fx := 0; fx < 8; fx++ {
+			x0 := int(cx + r*math.Sin((u+float64(fx*gs))/r))
+			x1 := int(cx + r*math.Sin((u+float64((fx+1)*gs))/r))
+			if x1 <= x0 {
+				continue // squeezed to nothing at the edge
+			}
+			for fy, bits := range glyph {
+				if bits&(1<<uint(fx)) != 0 {
+					fb.FillRect(x0, y+fy*gs, x1-x0, gs, col)
+				}
+			}
+		}
+	}
+}
+
+// TextWriter types pages of text onto the screen a character at a time,
+// behind a blinking cursor, holds each finished page and then clears for
+// the next, looping back to the first.
+//
+// Cue params: "pages" (list of strings, lines split on "\n"), or "text"
+// for a single page, "rate" (characters per second), "hold" (seconds a
+// finished page stays up), "scale", "palette", "color_step" and "cycle"
+// (as for CharScroller).
+type TextWriter struct {
+	pages  [][]string
+	counts []int // characters on each page
+	rate   float64
+	hold   float64
+	scale  int
+	colors charColors
+
+	time  float64
+	page  int
+	typed float64 // characters typed on this page
+	held  float64 // seconds the finished page has been up
+}
+
+func NewTextWriter() *TextWriter {
+	t := &TextWriter{}
+	t.Configure(nil)
+	return t
+}
+
+// Configure applies cue params; see Configurable.
+func (t *TextWriter) Configure(p Params) error {
+	var pages []string
+	if list, ok := p["pages"].([]any); ok && len(list) > 0 {
+		for i, v := range list {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("pages[%d]: must be a string", i)
+			}
+			pages = append(pages, s)
+		}
+	} else {
+		pages = []string{p.String("text", "VGA-GO\n\nA DEMO ENGINE IN GO\nFOR THE OLD SCHOOL")}
+	}
+	if err := t.colors.configure(p); err != nil {
+		return err
+	}
+
+	t.pages = t.pages[:0]
+	t.counts = t.counts[:0]
+	for _, page := range pages {
+		lines := strings.Split(page, "\n")
+		n := 0
+		for _, line := range lines {
+			n += len(line)
+		}
+		t.pages = append(t.pages, lines)
+		t.counts = append(t.counts, n)
+	}
+	// The old position may be past the end of the new pages.
+	t.page, t.typed, t.held = 0, 0, 0
+	t.rate = math.Max(p.Float("rate", 20), 1)
+	t.hold = p.Float("hold", 3)
+	t.scale = max(p.Int("scale", 1), 1)
+	return nil
+}
+
+func (t *TextWriter) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(t.colors.pal)
+	vga.LoadFontFromPNG("assets/font1.png")
+	t.page, t.typed, t.held = 0, 0, 0
+}
+
+func (t *TextWriter) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	t.time += dt * tempo
+	t.colors.phase += dt * tempo * t.colors.cycle
+	if n := float64(t.counts[t.page]); t.typed < n {
+		t.typed = math.Min(t.typed+dt*tempo*t.rate, n)
+		return
+	}
+	t.held += dt
+	if t.held >= t.hold {
+		t.page = (t.page + 1) % len(t.pages)
+		t.typed, t.held = 0, 0
+	}
+}
+
+func (t *TextWriter) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	if fb.Text != nil {
+		t.drawCells(fb.Text)
+		return
+	}
+	gs := glyphScale(fb, t.scale)
+	cw, lh := 8*gs, 10*gs
+	lines := t.pages[t.page]
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, len(line))
+	}
+	// The page is centred as a block, lines left-aligned within it.
+	x0 := (fb.Width - widest*cw) / 2
+	y0 := (fb.Height - len(lines)*lh) / 2
+
+	typed := int(t.typed)
+	i := 0 // running character index
+	cx, cy := x0, y0
+	for l, line := range lines {
+		y := y0 + l*lh
+		for j := 0; j < len(line) && i+j < typed; j++ {
+			drawGlyph(fb, x0+j*cw, y, line[j], gs, gs, t.colors.at(i+j))
+		}
+		// The cursor sits after the last character typed, moving on to
+		// the next line once this one is finished.
+		if typed < i+len(line) || l == len(lines)-1 {
+			cx, cy = x0+(typed-i)*cw, y
+			break
+		}
+		i += len(line)
+	}
+	if math.Mod(t.time*4, 2) < 1 {
+		fb.FillRect(cx, cy, cw, cw, t.colors.at(typed))
+	}
+}
+
+// drawCells types the page into a text mode's character buffer instead,
+// each character in the text colour nearest its palette colour.
+func (t *TextWriter) drawCells(screen *vga.TextScreen) {
+	screen.Clear(0)
+	lines := t.pages[t.page]
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, len(line))
+	}
+	x0 := (screen.Cols - widest) / 2
+	y0 := (screen.Rows - len(lines)) / 2
+
+	typed := int(t.typed)
+	i := 0
+	cx, cy := x0, y0
+	for l, line := range lines {
+		for j := 0; j < len(line) && i+j < typed; j++ {
+			screen.Put(x0+j, y0+l, line[j], t.textColor(i+j))
+		}
+		if typed < i+len(line) || l == len(lines)-1 {
+			cx, cy = x0+typed-i, y0+l
+			break
+		}
+		i += len(line)
+	}
+	if math.Mod(t.time*4, 2) < 1 {
+		screen.Put(cx, cy, ' ', t.textColor(typed)<<4) // block cursor
+	}
+}
+
+// textColor returns the text attribute colour for character i, never
+// black, which would make the cell transparent.
+func (t *TextWriter) textColor(i int) byte {
+	if c := vga.TextColor(t.colors.pal[t.colors.at(i)]); c != 0 {
+		return c
+	}
+	return 8
+}
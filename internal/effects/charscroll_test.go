@@ -0,0 +1,46 @@
+package effects
+
+import (
+	"testing"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Reconfiguring with fewer pages while a later page is up starts over.
+func TestTextWriterReconfigure(t *testing.T) {
+	fb := vga.NewFramebuffer(vga.DefaultPalette())
+	w := NewTextWriter()
+	if err := w.Configure(Params{"pages": []any{"ONE", "TWO", "THREE"}, "hold": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+	w.Init(fb)
+	for w.page != 2 {
+		w.Update(1, music.FrameInfo{})
+	}
+	if err := w.Configure(Params{"text": "ONLY"}); err != nil {
+		t.Fatal(err)
+	}
+	if w.page != 0 || w.typed != 0 || w.held != 0 {
+		t.Errorf("after Configure: page %d, typed %v, held %v", w.page, w.typed, w.held)
+	}
+}
+
+// A bad cue leaves the pages as they were.
+func TestTextWriterConfigureErrors(t *testing.T) {
+	for name, p := range map[string]Params{
+		"non-string page": {"pages": []any{"ONE", 2.0}},
+		"bad palette":     {"text": "NEW", "palette": "nope"},
+	} {
+		w := NewTextWriter()
+		if err := w.Configure(Params{"pages": []any{"ONE", "TWO"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Configure(p); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if len(w.pages) != 2 || w.pages[1][0] != "TWO" {
+			t.Errorf("%s: pages changed to %q", name, w.pages)
+		}
+	}
+}
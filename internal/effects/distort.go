@@ -0,0 +1,215 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Distort warps a source image. Modes:
+//
+//   - "wobble": rows and columns slide on travelling sine waves
+//   - "lens": a magnifying glass wanders over the image
+//   - "swirl": the centre twists, more strongly towards the middle
+//   - "kaleidoscope": a wedge of the image, drifting and turning, mirrored
+//     round the centre "folds" times
+//
+// Distort is a Filter; standalone, its source is set by the cue like
+// Water's.
+//
+// Cue params: "mode", "strength" (a number or keyframes; 1 is the normal
+// amount, 0 leaves the image alone except for the kaleidoscope, where it
+// adds a twist), "speed", "folds" (kaleidoscope mirrors, 2-16), "source"
+// and "image" (see Water).
+type Distort struct {
+	mode     string
+	strength motion
+	speed    float64
+	folds    int
+	source   filterSource
+
+	time float64
+	beat float64
+
+	// Polar coordinates of each pixel around the screen centre, rebuilt
+	// when the size changes.
+	w, h     int
+	distLUT  []float32
+	angleLUT []float32
+
+	// Per-frame state, set by DrawFrom.
+	src      *vga.Framebuffer
+	rowShift []int
+	colShift []int
+	lensX    int
+	lensY    int
+	amount   float64
+}
+
+func NewDistort() *Distort {
+	d := &Distort{}
+	d.Configure(nil)
+	return d
+}
+
+// Configure applies cue params; see Configurable.
+func (d *Distort) Configure(p Params) error {
+	mode := p.String("mode", "wobble")
+	switch mode {
+	case "wobble", "lens", "swirl", "kaleidoscope":
+	default:
+		return fmt.Errorf("mode: unknown mode %q", mode)
+	}
+	strength, err := p.animated("strength", 1)
+	if err != nil {
+		return err
+	}
+	folds := p.Int("folds", 6)
+	if folds < 2 || folds > 16 {
+		return fmt.Errorf("folds: must be 2-16")
+	}
+	// The source changes nothing unless it succeeds, so it goes last.
+	if err := d.source.configure(p); err != nil {
+		return err
+	}
+
+	d.mode, d.strength, d.folds = mode, strength, folds
+	d.speed = p.Float("speed", 1)
+	return nil
+}
+
+func (d *Distort) Init(fb *vga.Framebuffer) {
+	d.source.init(fb)
+}
+
+func (d *Distort) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	d.time += dt * tempo * d.speed
+	d.strength.update(dt, tempo, sync)
+	d.beat = sync.BeatPulse()
+}
+
+// buildLUTs computes the per-pixel distance and angle tables.
+func (d *Distort) buildLUTs(w, h int) {
+	if d.w == w && d.h == h {
+		return
+	}
+	d.w, d.h = w, h
+	d.distLUT = make([]float32, w*h)
+	d.angleLUT = make([]float32, w*h)
+	d.rowShift = make([]int, h)
+	d.colShift = make([]int, w)
+	cx, cy := float64(w)/2, float64(h)/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			d.distLUT[y*w+x] = float32(math.Sqrt(dx*dx + dy*dy))
+			d.angleLUT[y*w+x] = float32(math.Atan2(dy, dx))
+		}
+	}
+}
+
+func (d *Distort) Draw(fb *vga.Framebuffer) {
+	if !d.source.ready(fb) {
+		d.Init(fb)
+	}
+	d.DrawFrom(fb, d.source.fb)
+}
+
+// DrawFrom renders the distorted src into dst; see Filter.
+func (d *Distort) DrawFrom(dst, src *vga.Framebuffer) {
+	d.buildLUTs(dst.Width, dst.Height)
+	d.src = src
+	d.amount = d.strength.value
+	w, h := float64(d.w), float64(d.h)
+	switch d.mode {
+	case "wobble":
+		// Waves grow a little on the beat.
+		amp := d.amount * w * 0.03 * (1 + 0.5*d.beat)
+		for y := range d.rowShift {
+			d.rowShift[y] = int(amp * math.Sin(float64(y)*0.05+d.time*3))
+		}
+		for x := range d.colShift {
+			d.colShift[x] = int(amp * 0.6 * math.Sin(float64(x)*0.04+d.time*2.3))
+		}
+	case "lens":
+		d.lensX = int(w * (0.5 + 0.3*math.Sin(d.time*0.9)))
+		d.lensY = int(h * (0.5 + 0.3*math.Sin(d.time*1.3+1)))
+	}
+	DrawParallel(dst, d)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (d *Distort) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	cx, cy := float64(d.w)/2, float64(d.h)/2
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+d.w]
+		switch d.mode {
+		case "wobble":
+			for x := range row {
+				row[x] = d.sample(x+d.rowShift[y], y+d.colShift[x])
+			}
+		case "lens":
+			d.lensRow(row, y)
+		case "swirl":
+			radius := math.Min(cx, cy) * 1.2
+			for x := range row {
+				i := y*d.w + x
+				r := float64(d.distLUT[i])
+				a := float64(d.angleLUT[i])
+				if r < radius {
+					k := 1 - r/radius
+					a += d.amount * 3 * k * k * math.Sin(d.time)
+				}
+				s, c := math.Sincos(a)
+				row[x] = d.sample(int(cx+r*c+0.5), int(cy+r*s+0.5))
+			}
+		case "kaleidoscope":
+			wedge := 2 * math.Pi / float64(d.folds)
+			turn := d.time * 0.3
+			// The wedge's apex drifts over the source.
+			ox := cx + cx*0.4*math.Sin(d.time*0.7)
+			oy := cy + cy*0.4*math.Sin(d.time*0.5+2)
+			for x := range row {
+				i := y*d.w + x
+				r := float64(d.distLUT[i])
+				a := math.Mod(float64(d.angleLUT[i])+math.Pi+r*0.01*d.amount, wedge)
+				if a > wedge/2 {
+					a = wedge - a // mirror alternate wedges
+				}
+				s, c := math.Sincos(a + turn)
+				row[x] = d.sample(int(ox+r*c+0.5), int(oy+r*s+0.5))
+			}
+		}
+	}
+}
+
+// lensRow renders row y with the lens: inside its circle, samples are
+// pulled towards the centre, magnifying it most in the middle.
+func (d *Distort) lensRow(row []byte, y int) {
+	radius := float64(d.h) * 0.22 * (1 + 0.1*d.beat)
+	k := math.Min(d.amount*0.6, 0.95)
+	dy := float64(y - d.lensY)
+	for x := range row {
+		dx := float64(x - d.lensX)
+		if q := dx*dx + dy*dy; q < radius*radius {
+			// Scale 1-k at the centre, back to 1 at the rim.
+			s := 1 - k*(1-q/(radius*radius))
+			row[x] = d.sample(d.lensX+int(dx*s), d.lensY+int(dy*s))
+			continue
+		}
+		row[x] = d.sample(x, y)
+	}
+}
+
+// sample reads src at (x, y), clamped to its edges.
+func (d *Distort) sample(x, y int) byte {
+	x = min(max(x, 0), d.src.Width-1)
+	y = min(max(y, 0), d.src.Height-1)
+	return d.src.Pixels[y*d.src.Stride+x]
+}
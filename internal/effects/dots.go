@@ -0,0 +1,373 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+const (
+	maxDots = 2048
+	// ballLevels is how many pre-rendered ball sprites there are, from the
+	// nearest (largest, brightest) to the farthest.
+	ballLevels = 8
+)
+
+// dotShape generates n points of a named shape, roughly unit radius. Point
+// i of one shape morphs into point i of the next.
+func dotShape(name string, n int) ([]Vec3, error) {
+	pts := make([]Vec3, n)
+	golden := math.Pi * (3 - math.Sqrt(5))
+	for i := range pts {
+		// Fibonacci sphere: evenly spread, top to bottom.
+		y := 1 - 2*(float64(i)+0.5)/float64(n)
+		r := math.Sqrt(1 - y*y)
+		s, c := math.Sincos(float64(i) * golden)
+		pts[i] = Vec3{r * c, y, r * s}
+	}
+	switch name {
+	case "sphere":
+	case "cube":
+		// Push the sphere's points out onto the cube's surface.
+		for i, p := range pts {
+			m := math.Max(math.Abs(p.X), math.Max(math.Abs(p.Y), math.Abs(p.Z)))
+			pts[i] = p.Scale(0.75 / m)
+		}
+	case "torus":
+		sides := max(int(math.Sqrt(float64(n)/3)), 3)
+		segs := (n + sides - 1) / sides
+		for i := range pts {
+			su, cu := math.Sincos(2 * math.Pi * float64(i/sides) / float64(segs))
+			sv, cv := math.Sincos(2 * math.Pi * float64(i%sides) / float64(sides))
+			pts[i] = Vec3{(0.7 + 0.3*cv) * cu, 0.3 * sv, (0.7 + 0.3*cv) * su}
+		}
+	case "helix":
+		for i := range pts {
+			t := float64(i) / float64(n)
+			s, c := math.Sincos(t * 2 * math.Pi * 8)
+			pts[i] = Vec3{0.6 * c, 1 - 2*t, 0.6 * s}
+		}
+	default:
+		return nil, fmt.Errorf("shapes: unknown shape %q", name)
+	}
+	return pts, nil
+}
+
+// DotBall is a rotating cloud of dots that morphs from shape to shape on
+// the beat: single depth-shaded pixels, or vector balls (pre-rendered shaded
+// spheres, one sprite per depth level, drawn far to near).
+//
+// Cue params: "shapes" (list of "sphere", "torus", "cube" or "helix",
+// visited in turn), "dots" (count), "style" ("dots" or "balls"),
+// "morph_beats" (beats per shape, the last quarter spent morphing),
+// "colors" (up to 4 "#rrggbb", dots cycle through them), "ball_size"
+// (fraction of screen height), "rot_x"/"rot_y"/"rot_z" (a number is a spin
+// rate, keyframes give absolute angles), "scale" and "distance".
+type DotBall struct {
+	shapes     [][]Vec3
+	balls      bool
+	morphBeats float64
+	colors     int
+	pal        vga.Palette
+	ballSize   float64
+	rot        [3]motion
+	scale      float64
+	distance   float64
+
+	beats float64
+	pulse float64
+	cloud PointCloud
+
+	// Rebuilt when the framebuffer size changes.
+	spriteH int
+	sprites [numRamps][ballLevels]*vga.Sprite
+}
+
+func NewDotBall() *DotBall {
+	d := &DotBall{}
+	d.Configure(nil)
+	return d
+}
+
+var defaultDotColors = []color.RGBA{
+	{64, 160, 255, 255},
+	{255, 96, 64, 255},
+	{96, 240, 128, 255},
+	{255, 224, 64, 255},
+}
+
+// dotColors reads the "colors" list (up to numRamps) and returns how many
+// colours are in use and a palette with a shading ramp for each.
+func dotColors(p Params) (int, vga.Palette, error) {
+	colors := slices.Clone(defaultDotColors)
+	n := len(colors)
+	if list, ok := p["colors"].([]any); ok && len(list) > 0 {
+		n = min(len(list), numRamps)
+		for i, item := range list[:n] {
+			s, _ := item.(string)
+			c, err := parseColor(s)
+			if err != nil {
+				return 0, vga.Palette{}, fmt.Errorf("colors: %w", err)
+			}
+			colors[i] = c
+		}
+	}
+	return n, rampPalettes(colors), nil
+}
+
+// Configure applies cue params; see Configurable.
+func (d *DotBall) Configure(p Params) error {
+	n := p.Int("dots", 400)
+	if n < 1 || n > maxDots {
+		return fmt.Errorf("dots: must be 1-%d", maxDots)
+	}
+	names := []string{"sphere", "torus", "cube"}
+	if list, ok := p["shapes"].([]any); ok && len(list) > 0 {
+		names = names[:0]
+		for _, v := range list {
+			s, _ := v.(string)
+			names = append(names, s)
+		}
+	}
+
+	var balls bool
+	switch style := p.String("style", "balls"); style {
+	case "dots", "balls":
+		balls = style == "balls"
+	default:
+		return fmt.Errorf("style: unknown style %q", style)
+	}
+	colors, pal, err := dotColors(p)
+	if err != nil {
+		return err
+	}
+	var rot [3]motion
+	for i, key := range []string{"rot_x", "rot_y", "rot_z"} {
+		if rot[i], err = p.motion(key, []float64{0.3, 0.7, 0.2}[i]); err != nil {
+			return err
+		}
+	}
+	shapes := make([][]Vec3, 0, len(names))
+	for _, name := range names {
+		pts, err := dotShape(name, n)
+		if err != nil {
+			return err
+		}
+		shapes = append(shapes, pts)
+	}
+
+	d.shapes, d.balls, d.colors, d.pal, d.rot = shapes, balls, colors, pal, rot
+	d.morphBeats = math.Max(p.Float("morph_beats", 8), 1)
+	d.ballSize = p.Float("ball_size", 0.045)
+	d.scale = p.Float("scale", 1)
+	d.distance = p.Float("distance", 3.2)
+
+	d.cloud.Points = make([]Vec3, n)
+	d.cloud.Near = 0.1
+	d.spriteH = 0 // colours or size may have changed
+	d.beats = 0
+	return nil
+}
+
+func (d *DotBall) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(d.pal)
+}
+
+func (d *DotBall) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	for i := range d.rot {
+		d.rot[i].update(dt, tempo, sync)
+	}
+	d.beats += dt * tempo * 2 // 120 BPM is two beats a second
+	d.pulse = sync.BeatPulse()
+}
+
+// buildSprites renders a shaded ball per colour ramp and depth level.
+// Farther levels are smaller and darker; index 0 is transparent.
+func (d *DotBall) buildSprites(fb *vga.Framebuffer) {
+	d.spriteH = fb.Height
+	r0 := math.Max(d.ballSize*float64(fb.Height), 1.5)
+	for k := range d.sprites {
+		for l := range d.sprites[k] {
+			r := r0 * (1 - 0.06*float64(l))
+			dim := 1 - 0.09*float64(l)
+			size := int(2*r) + 1
+			s := vga.NewSprite(size, size)
+			c := float64(size-1) / 2
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					nx, ny := (float64(x)-c)/r, (c-float64(y))/r
+					q := nx*nx + ny*ny
+					if q > 1 {
+						continue
+					}
+					n := Vec3{nx, ny, -math.Sqrt(1 - q)}
+					shade := intensity(n) * dim
+					// Specular glint towards the light.
+					shade += 0.5 * math.Pow(math.Max(0, n.Dot(toLight)), 12)
+					idx := 1 + int(math.Min(shade, 1)*(rampSize-2))
+					s.SetPixel(x, y, byte(k*rampSize+idx))
+				}
+			}
+			d.sprites[k][l] = s
+		}
+	}
+}
+
+func (d *DotBall) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	if d.balls && d.spriteH != fb.Height {
+		d.buildSprites(fb)
+	}
+
+	// Morph during the last quarter of each shape's beats.
+	stage := d.beats / d.morphBeats
+	k := int(stage) % len(d.shapes)
+	t := (stage - math.Floor(stage) - 0.75) * 4
+	t = math.Max(t, 0)
+	t = t * t * (3 - 2*t)
+	from, to := d.shapes[k], d.shapes[(k+1)%len(d.shapes)]
+	for i := range d.cloud.Points {
+		d.cloud.Points[i] = from[i].Lerp(to[i], t)
+	}
+
+	xf := RotationXYZ(d.rot[0].value, d.rot[1].value, d.rot[2].value).Scaled(d.scale * (1 + 0.1*d.pulse))
+	focal := 1.5 * float64(fb.Height)
+	margin := 0
+	if d.balls {
+		margin = d.sprites[0][0].Width
+	}
+	pts := d.cloud.Project(fb, xf, Vec3{0, 0, d.distance}, focal, margin)
+	SortFarToNear(pts)
+
+	// Depth levels span the object's depth around its centre.
+	for _, p := range pts {
+		rel := (p.Z - d.distance) / (2 * d.scale)
+		level := min(max(int((rel+0.5)*ballLevels), 0), ballLevels-1)
+		ramp := p.Index % d.colors
+		if d.balls {
+			s := d.sprites[ramp][level]
+			fb.DrawSprite(p.X-s.Width/2, p.Y-s.Height/2, s)
+			continue
+		}
+		if p.X >= 0 && p.X < fb.Width && p.Y >= 0 && p.Y < fb.Height {
+			shade := (rampSize - 2) * (ballLevels - level) / ballLevels
+			fb.Pixels[p.Y*fb.Stride+p.X] = byte(ramp*rampSize + 1 + shade)
+		}
+	}
+}
+
+// DotTunnel flies through a tunnel of dot rings snaking along sine paths.
+// Rings recycle as they pass the camera; nearer dots are brighter and
+// larger, and rings flash on the beat.
+//
+// Cue params: "rings", "dots" (per ring), "radius", "twist" (roll rate),
+// "speed" (rings per second at 120 BPM), "colors" (up to 4 "#rrggbb", one
+// per ring in turn).
+type DotTunnel struct {
+	rings   int
+	perRing int
+	radius  float64
+	twist   float64
+	speed   float64
+	colors  int
+	pal     vga.Palette
+
+	pos   float64 // camera position along the tunnel, in rings
+	roll  float64
+	pulse float64
+	cloud PointCloud
+}
+
+func NewDotTunnel() *DotTunnel {
+	t := &DotTunnel{}
+	t.Configure(nil)
+	return t
+}
+
+// Configure applies cue params; see Configurable.
+func (t *DotTunnel) Configure(p Params) error {
+	rings, perRing := p.Int("rings", 48), p.Int("dots", 24)
+	if rings < 1 || perRing < 1 || rings*perRing > maxDots {
+		return fmt.Errorf("rings: rings times dots must be 1-%d", maxDots)
+	}
+	colors, pal, err := dotColors(p)
+	if err != nil {
+		return err
+	}
+
+	t.rings, t.perRing = rings, perRing
+	t.colors, t.pal = colors, pal
+	t.radius = p.Float("radius", 1)
+	t.twist = p.Float("twist", 0.4)
+	t.speed = p.Float("speed", 6)
+	t.cloud.Points = make([]Vec3, t.rings*t.perRing)
+	t.cloud.Near = 0.05
+	return nil
+}
+
+func (t *DotTunnel) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(t.pal)
+}
+
+func (t *DotTunnel) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	t.pos += t.speed * tempo * dt
+	t.roll += t.twist * tempo * dt
+	t.pulse = sync.BeatPulse()
+}
+
+// tunnelCentre is the tunnel's centre line at distance z along it, in
+// rings.
+func tunnelCentre(z float64) Vec3 {
+	return Vec3{0.6 * math.Sin(z*0.11), 0.4 * math.Sin(z*0.07+1), 0}
+}
+
+func (t *DotTunnel) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	const spacing = 0.5 // world units between rings
+
+	// Ring j is the first at or beyond the camera; the centre line is
+	// relative to the camera's, so the tunnel bends around the view.
+	first := int(math.Floor(t.pos))
+	cam := tunnelCentre(t.pos)
+	for j := 0; j < t.rings; j++ {
+		ring := first + j
+		z := float64(ring) - t.pos
+		c := tunnelCentre(float64(ring)).Sub(cam)
+		for i := 0; i < t.perRing; i++ {
+			s, co := math.Sincos(2*math.Pi*float64(i)/float64(t.perRing) + float64(ring)*0.15)
+			t.cloud.Points[j*t.perRing+i] = Vec3{c.X + co*t.radius, c.Y + s*t.radius, z * spacing}
+		}
+	}
+
+	focal := 0.8 * float64(fb.Height)
+	pts := t.cloud.Project(fb, RotationXYZ(0, 0, t.roll), Vec3{}, focal, 1)
+	SortFarToNear(pts)
+	far := float64(t.rings) * spacing
+	for _, p := range pts {
+		ring := first + p.Index/t.perRing
+		bright := 1 - p.Z/far
+		if ring%4 == 0 {
+			bright += 0.4 * t.pulse
+		}
+		shade := byte(1 + min(bright, 1)*(rampSize-2))
+		col := byte(((ring%t.colors)+t.colors)%t.colors*rampSize) + shade
+		if p.Z < far/4 {
+			// Near dots are 2x2.
+			fb.FillRect(p.X, p.Y, 2, 2, col)
+		} else {
+			fb.SetPixelSafe(p.X, p.Y, col)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package effects
+
+import (
+	"fmt"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Filter is implemented by effects that transform an existing image instead
+// of drawing from scratch. DrawFrom renders into dst reading from src, a
+// framebuffer of the same size sharing dst's palette; src must not be
+// modified and is never the same buffer as dst.
+//
+// A filter gets its source in one of three ways:
+//   - from another effect's live output, chained with NewFilterChain;
+//   - from an image given in its cue params;
+//   - from the frame it was handed when it became active (the previous
+//     effect's last frame): the sequencer never clears the framebuffer, so
+//     Init can snapshot fb before drawing over it.
+//
+// The last two are up to each filter's Draw (see filterSource);
+// FilterChain only uses DrawFrom.
+type Filter interface {
+	Effect
+	DrawFrom(dst, src *vga.Framebuffer)
+}
+
+// FilterChain runs a source effect and passes its output through filters,
+// each reading the previous stage's result. The source effect owns the
+// palette.
+//
+// Cue params: "source" (params object for the source effect) and "filters"
+// (list of params objects, one per filter).
+type FilterChain struct {
+	source  Effect
+	filters []Filter
+	bufs    [2]*vga.Framebuffer
+}
+
+func NewFilterChain(source Effect, filters ...Filter) *FilterChain {
+	return &FilterChain{source: source, filters: filters}
+}
+
+// Configure passes nested params on to the stages; see Configurable.
+func (c *FilterChain) Configure(p Params) error {
+	sub, _ := p["source"].(map[string]any)
+	if err := configureLayer(c.source, sub); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	list, _ := p["filters"].([]any)
+	for i, f := range c.filters {
+		var sub map[string]any
+		if i < len(list) {
+			sub, _ = list[i].(map[string]any)
+		}
+		if err := configureLayer(f, sub); err != nil {
+			return fmt.Errorf("filters[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Init initialises the source only; filters size themselves in DrawFrom and
+// take the source's palette.
+func (c *FilterChain) Init(fb *vga.Framebuffer) {
+	c.source.Init(fb)
+}
+
+func (c *FilterChain) Update(dt float64, sync music.FrameInfo) {
+	c.source.Update(dt, sync)
+	for _, f := range c.filters {
+		f.Update(dt, sync)
+	}
+}
+
+func (c *FilterChain) Draw(fb *vga.Framebuffer) {
+	if len(c.filters) == 0 {
+		c.source.Draw(fb)
+		return
+	}
+	for i, b := range c.bufs {
+		if b == nil || b.Width != fb.Width || b.Height != fb.Height {
+			mode := fb.Mode
+			mode.Width, mode.Height = fb.Width, fb.Height
+			c.bufs[i] = vga.NewFramebufferMode(mode, fb.Palette)
+		}
+	}
+
+	// Ping-pong between the scratch buffers; the last filter writes to fb.
+	src := c.bufs[0]
+	src.Palette = fb.Palette
+	c.source.Draw(src)
+	if src.Palette != fb.Palette {
+		fb.SetPalette(src.Palette) // the source animated its palette
+	}
+	for i, f := range c.filters {
+		dst := fb
+		if i < len(c.filters)-1 {
+			dst = c.bufs[(i+1)%2]
+			dst.Palette = fb.Palette
+		}
+		f.DrawFrom(dst, src)
+		src = dst
+	}
+}
+
+// filterSource is a standalone filter's source image, chosen by the cue
+// params "source" ("image" or "previous") and "image" (see Params.Texture,
+// tiled across the screen).
+type filterSource struct {
+	previous bool
+	tex      *vga.Sprite
+	pal      vga.Palette
+	fb       *vga.Framebuffer
+}
+
+func (s *filterSource) configure(p Params) error {
+	var previous bool
+	switch src := p.String("source", "image"); src {
+	case "image", "previous":
+		previous = src == "previous"
+	default:
+		return fmt.Errorf("source: unknown source %q", src)
+	}
+	tex, pal, err := p.Texture("image", "xor")
+	if err != nil {
+		return err
+	}
+	s.previous, s.tex, s.pal = previous, tex, pal
+	return nil
+}
+
+// init captures the source for fb: a snapshot of fb itself, keeping its
+// palette, or the tiled image, setting fb's palette to the image's.
+func (s *filterSource) init(fb *vga.Framebuffer) {
+	if s.fb == nil || s.fb.Width != fb.Width || s.fb.Height != fb.Height {
+		mode := fb.Mode
+		mode.Width, mode.Height = fb.Width, fb.Height
+		s.fb = vga.NewFramebufferMode(mode, fb.Palette)
+	}
+	if s.previous {
+		s.fb.CopyFrom(fb)
+		return
+	}
+	fb.SetPalette(s.pal)
+	s.fb.Palette = s.pal
+	for y := 0; y < s.fb.Height; y++ {
+		ty := wrapTexel(y, s.tex.Height)
+		for x := 0; x < s.fb.Width; x++ {
+			s.fb.Pixels[y*s.fb.Stride+x] = s.tex.Pixels[ty*s.tex.Width+wrapTexel(x, s.tex.Width)]
+		}
+	}
+}
+
+// ready reports whether the source has been captured at fb's size.
+func (s *filterSource) ready(fb *vga.Framebuffer) bool {
+	return s.fb != nil && s.fb.Width == fb.Width && s.fb.Height == fb.Height
+}
@@ -9,7 +9,8 @@ import (
 
 // Fire is a classic bottom-up heat propagation fire effect.
 type Fire struct {
-	heat     [vga.Size]int
+	heat     []int
+	w, h     int
 	intensity float64
 }
 
@@ -19,11 +20,21 @@ func NewFire() *Fire {
 
 func (f *Fire) Init(fb *vga.Framebuffer) {
 	fb.SetPalette(vga.FirePalette())
+	f.resize(fb.Width, fb.Height)
 	for i := range f.heat {
 		f.heat[i] = 0
 	}
 }
 
+// resize reallocates the heat buffer when the target framebuffer size changes.
+func (f *Fire) resize(w, h int) {
+	if f.w == w && f.h == h {
+		return
+	}
+	f.w, f.h = w, h
+	f.heat = make([]int, w*h)
+}
+
 func (f *Fire) Update(dt float64, sync music.FrameInfo) {
 	f.intensity = 1.0
 	if sync.BPM > 0 {
@@ -43,50 +54,55 @@ func (f *Fire) Update(dt float64, sync music.FrameInfo) {
 }
 
 func (f *Fire) Draw(fb *vga.Framebuffer) {
+	f.resize(fb.Width, fb.Height)
+	width, height := f.w, f.h
+
 	// Seed the bottom row with random hot pixels
 	base := int(200.0 * f.intensity)
 	if base > 255 {
 		base = 255
 	}
-	for x := 0; x < vga.Width; x++ {
-		f.heat[(vga.Height-1)*vga.Width+x] = rand.Intn(base + 1)
-		if vga.Height >= 2 {
-			f.heat[(vga.Height-2)*vga.Width+x] = rand.Intn(base + 1)
+	for x := 0; x < width; x++ {
+		f.heat[(height-1)*width+x] = rand.Intn(base + 1)
+		if height >= 2 {
+			f.heat[(height-2)*width+x] = rand.Intn(base + 1)
 		}
 	}
 
 	// Propagate heat upward with averaging + decay
-	for y := 0; y < vga.Height-2; y++ {
-		for x := 0; x < vga.Width; x++ {
+	for y := 0; y < height-2; y++ {
+		for x := 0; x < width; x++ {
 			left := x - 1
 			if left < 0 {
 				left = 0
 			}
 			right := x + 1
-			if right >= vga.Width {
-				right = vga.Width - 1
+			if right >= width {
+				right = width - 1
 			}
 
-			sum := f.heat[(y+1)*vga.Width+left] +
-				f.heat[(y+1)*vga.Width+x] +
-				f.heat[(y+1)*vga.Width+right] +
-				f.heat[(y+2)*vga.Width+x]
+			sum := f.heat[(y+1)*width+left] +
+				f.heat[(y+1)*width+x] +
+				f.heat[(y+1)*width+right] +
+				f.heat[(y+2)*width+x]
 
 			avg := sum / 4
 			decay := avg - 2
 			if decay < 0 {
 				decay = 0
 			}
-			f.heat[y*vga.Width+x] = decay
+			f.heat[y*width+x] = decay
 		}
 	}
 
 	// Write to framebuffer
-	for i := range f.heat {
-		v := f.heat[i]
-		if v > 255 {
-			v = 255
+	for y := 0; y < height; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+width]
+		for x, v := range f.heat[y*width : (y+1)*width] {
+			if v > 255 {
+				v = 255
+			}
+			row[x] = byte(v)
 		}
-		fb.Pixels[i] = byte(v)
 	}
 }
@@ -0,0 +1,188 @@
+package effects
+
+import (
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+const (
+	maxParticles = 8192
+	maxRockets   = 16
+	// fireworksStep is the fixed simulation step. Launches happen in steps
+	// too, notes on the first step after the frame they are heard in, so a
+	// seed replays the same show at any frame rate.
+	fireworksStep = 1.0 / 60
+	// Fireworks simulate in a 320x200 space, scaled to the screen.
+	fireworksW, fireworksH = 320.0, 200.0
+)
+
+// noteTracker spots notes being played: a channel's volume jumping up.
+type noteTracker [music.MaxChannels]int
+
+// scan calls fn for each channel whose volume rose by more than 32 since
+// the previous scan.
+func (t *noteTracker) scan(sync music.FrameInfo, fn func(ch, vol int)) {
+	for ch := 0; ch < sync.NumChannels; ch++ {
+		v := sync.ChannelVol[ch]
+		if v > t[ch]+32 {
+			fn(ch, v)
+		}
+		t[ch] = v
+	}
+}
+
+// note is a note heard but not launched yet, with the channel count of
+// the frame it was heard in.
+type note struct {
+	ch, channels int
+}
+
+// rocket is a firework on its way up, trailing sparks until it bursts at
+// the top of its climb.
+type rocket struct {
+	x, y, vx, vy float64
+	ramp         int
+	trail        Emitter
+}
+
+// Fireworks launches a rocket for every note played (from the channel's
+// column of the screen, in the channel's colour) and bursts it into sparks
+// that fall under gravity and fade through their colour ramp, blended
+// additively. With no music, rockets launch at random.
+//
+// Cue params: "sparks" (per burst), "gravity", "drag", "auto" (launches
+// per second without music), "colors" (up to 4 "#rrggbb") and "seed".
+type Fireworks struct {
+	sparks  int
+	gravity float64
+	drag    float64
+	auto    float64
+	colors  int
+	pal     vga.Palette
+	seed    int64
+
+	ps       *ParticleSystem
+	rockets  [maxRockets]rocket
+	nRockets int
+	notes    noteTracker
+	queued   []note
+	channels int     // music channels, 0 without music
+	pending  float64 // unsimulated time, in seconds
+
+	blend    *vga.BlendTable
+	blendPal vga.Palette // palette blend was built for
+}
+
+func NewFireworks() *Fireworks {
+	f := &Fireworks{ps: NewParticleSystem(maxParticles, 1)}
+	f.Configure(nil)
+	return f
+}
+
+// Configure applies cue params; see Configurable.
+func (f *Fireworks) Configure(p Params) error {
+	colors, pal, err := dotColors(p)
+	if err != nil {
+		return err
+	}
+	f.colors, f.pal = colors, pal
+	f.sparks = min(max(p.Int("sparks", 150), 1), maxParticles/maxRockets)
+	f.gravity = p.Float("gravity", 60)
+	f.drag = p.Float("drag", 0.6)
+	f.auto = p.Float("auto", 1.2)
+	f.seed = int64(p.Int("seed", 1))
+	return nil
+}
+
+func (f *Fireworks) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(f.pal)
+	if f.blend == nil || f.blendPal != f.pal {
+		f.blend = vga.AdditiveTable(f.pal)
+		f.blendPal = f.pal
+	}
+	f.ps.Reset(f.seed)
+	f.ps.Gravity, f.ps.Drag = f.gravity, f.drag
+	f.nRockets = 0
+	f.queued = f.queued[:0]
+	f.pending = 0
+}
+
+// launch sends a rocket up from x (0-1 across the screen) in colour ramp.
+func (f *Fireworks) launch(x float64, ramp int) {
+	if f.nRockets == maxRockets {
+		return
+	}
+	rng := f.ps.Rng
+	// Burst between a quarter and half way down the screen: the apex of
+	// a climb from the bottom under gravity.
+	climb := fireworksH * (0.5 + 0.25*rng.Float64())
+	base := byte(ramp * rampSize)
+	f.rockets[f.nRockets] = rocket{
+		x:    x * fireworksW,
+		y:    fireworksH,
+		vx:   (rng.Float64()*2 - 1) * 15,
+		vy:   -math.Sqrt(2 * f.gravity * climb),
+		ramp: ramp,
+		trail: Emitter{
+			Rate: 60, Angle: math.Pi / 2, Spread: 0.3, Speed: 12, Life: 0.5,
+			Ramp: ColorRamp{base + rampSize/2, base + 1},
+		},
+	}
+	f.nRockets++
+}
+
+func (f *Fireworks) Update(dt float64, sync music.FrameInfo) {
+	f.notes.scan(sync, func(ch, vol int) {
+		f.queued = append(f.queued, note{ch, sync.NumChannels})
+	})
+	f.channels = sync.NumChannels
+
+	f.pending += dt
+	for ; f.pending >= fireworksStep; f.pending -= fireworksStep {
+		f.step()
+	}
+}
+
+// step advances the rockets and particles by one fixed step.
+func (f *Fireworks) step() {
+	const dt = fireworksStep
+	rng := f.ps.Rng
+	for _, n := range f.queued {
+		x := (float64(n.ch) + 0.2 + 0.6*rng.Float64()) / float64(n.channels)
+		f.launch(x, n.ch%f.colors)
+	}
+	f.queued = f.queued[:0]
+	if f.channels == 0 && rng.Float64() < f.auto*dt {
+		f.launch(0.1+0.8*rng.Float64(), rng.Intn(f.colors))
+	}
+
+	for i := 0; i < f.nRockets; {
+		r := &f.rockets[i]
+		r.vy += f.gravity * dt
+		r.x += r.vx * dt
+		r.y += r.vy * dt
+		r.trail.X, r.trail.Y = r.x, r.y
+		r.trail.Emit(f.ps, dt)
+		if r.vy < 0 {
+			i++
+			continue
+		}
+		// Top of the climb: burst, with a white-hot core that fades
+		// through the colour.
+		base := byte(r.ramp * rampSize)
+		f.ps.Burst(r.x, r.y, f.sparks, 70, 1.6, ColorRamp{base + rampSize - 1, base + 1})
+		f.ps.Burst(r.x, r.y, f.sparks/4, 25, 0.6, ColorRamp{base + rampSize - 1, base + rampSize/2})
+		f.nRockets--
+		f.rockets[i] = f.rockets[f.nRockets]
+	}
+	f.ps.Update(dt)
+}
+
+func (f *Fireworks) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	sx := float64(fb.Width) / fireworksW
+	sy := float64(fb.Height) / fireworksH
+	f.ps.Draw(fb, sx, sy, max(int(sx), 1), f.blend)
+}
@@ -0,0 +1,54 @@
+package effects
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// The same seed gives the same show whatever the frame rate.
+func TestFireworksFrameRate(t *testing.T) {
+	run := func(fps int) []Particle {
+		f := NewFireworks()
+		if err := f.Configure(Params{"auto": 20.0, "seed": 7.0}); err != nil {
+			t.Fatal(err)
+		}
+		f.Init(vga.NewFramebuffer(vga.DefaultPalette()))
+		// Half a step past 2 seconds, clear of any rounding at the edge.
+		for i := 0; i < 2*fps+fps/120; i++ {
+			f.Update(1/float64(fps), music.FrameInfo{})
+		}
+		return slices.Clone(f.ps.Live())
+	}
+	want := run(120)
+	if len(want) == 0 {
+		t.Fatal("nothing launched")
+	}
+	for _, fps := range []int{240, 600} {
+		if got := run(fps); !slices.Equal(got, want) {
+			t.Errorf("%d fps: %d particles differ from %d at 120 fps", fps, len(got), len(want))
+		}
+	}
+}
+
+// A note heard on a frame too short to step still launches from its
+// channel when the music has stopped by the next frame.
+func TestFireworksNoteAfterMusicStops(t *testing.T) {
+	f := NewFireworks()
+	if err := f.Configure(Params{"auto": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+	f.Init(vga.NewFramebuffer(vga.DefaultPalette()))
+	playing := music.FrameInfo{NumChannels: 4}
+	playing.ChannelVol[3] = 255
+	f.Update(fireworksStep/2, playing)
+	f.Update(fireworksStep, music.FrameInfo{})
+	if f.nRockets != 1 {
+		t.Fatalf("%d rockets, want 1", f.nRockets)
+	}
+	if x := f.rockets[0].x / fireworksW; !(x >= 0.75 && x <= 1) {
+		t.Errorf("launched at %v across the screen, want channel 3 of 4", x)
+	}
+}
@@ -0,0 +1,213 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// floorShades is how many texture colours the floor keeps: the palette
+// holds them at each of fogLevels distances, darkening into black.
+const floorShades = 256 / fogLevels
+
+// Floor is a Mode-7 style perspective plane: a tiled texture projected onto
+// an infinite floor (and optionally a ceiling), one scanline at a time. Each
+// scanline is a single distance, so it is a straight scaled and rotated
+// walk through the texture. The camera glides forward at the music's tempo
+// and the plane fogs into black towards the horizon.
+//
+// Cue params: "texture" ("checker", "xor" or a PNG path), "palette"
+// (overrides the texture's palette), "ceiling" (true to mirror the plane
+// above the horizon), "speed" (texels per second at 120 BPM), "turn" (a
+// number is a turn rate, keyframes give the heading), "height" (camera
+// height in texels), "horizon" (fraction of screen height) and "fog"
+// (distance at which the plane is fully black).
+type Floor struct {
+	tex     *vga.Sprite
+	texels  []byte // tex remapped to floorShades colours
+	pal     vga.Palette
+	ceiling bool
+	speed   float64
+	turn    motion
+	height  motion
+	horizon motion
+	fogDist float64
+
+	camX, camY float64
+
+	// Per-frame projection, set by Draw.
+	horizonY int
+	sin, cos float64
+	proj     float64
+	sky      byte
+}
+
+func NewFloor() *Floor {
+	f := &Floor{}
+	f.Configure(nil)
+	return f
+}
+
+// Configure applies cue params; see Configurable.
+func (f *Floor) Configure(p Params) error {
+	tex, pal, err := p.Texture("texture", "checker")
+	if err != nil {
+		return err
+	}
+	if custom, ok, err := p.Palette("palette"); err != nil {
+		return err
+	} else if ok {
+		pal = custom
+	}
+	fogDist := p.Float("fog", 1024)
+	if fogDist <= 0 {
+		return fmt.Errorf("fog: must be positive")
+	}
+	turn, err := p.motion("turn", 0.15)
+	if err != nil {
+		return err
+	}
+	height, err := p.animated("height", 24)
+	if err != nil {
+		return err
+	}
+	horizon, err := p.animated("horizon", 0.4)
+	if err != nil {
+		return err
+	}
+
+	f.tex = tex
+	f.pal, f.texels = floorPalette(tex, pal)
+	f.sky = byte((fogLevels - 1) * floorShades)
+	f.ceiling = p.Bool("ceiling", false)
+	f.speed = p.Float("speed", 96)
+	f.fogDist = fogDist
+	f.turn, f.height, f.horizon = turn, height, horizon
+	return nil
+}
+
+// floorPalette reduces the texture to floorShades representative colours,
+// spread evenly over the indices it uses (which follows the gradient for
+// ramps, XOR and greyscale textures), and lays them out at every fog level.
+// It returns the palette and the texture remapped into it.
+func floorPalette(tex *vga.Sprite, src vga.Palette) (vga.Palette, []byte) {
+	var used [256]bool
+	for _, c := range tex.Pixels {
+		used[c] = true
+	}
+	var idx []int
+	for c, u := range used {
+		if u {
+			idx = append(idx, c)
+		}
+	}
+	reps := idx
+	if len(idx) > floorShades {
+		reps = make([]int, floorShades)
+		for i := range reps {
+			reps[i] = idx[(2*i+1)*len(idx)/(2*floorShades)]
+		}
+	}
+
+	var remap [256]byte
+	for _, c := range idx {
+		best, bestDist := 0, math.MaxInt
+		for i, r := range reps {
+			dr := int(src[c].R) - int(src[r].R)
+			dg := int(src[c].G) - int(src[r].G)
+			db := int(src[c].B) - int(src[r].B)
+			if d := dr*dr + dg*dg + db*db; d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		remap[c] = byte(best)
+	}
+	texels := make([]byte, len(tex.Pixels))
+	for i, c := range tex.Pixels {
+		texels[i] = remap[c]
+	}
+
+	var pal vga.Palette
+	black := color.RGBA{0, 0, 0, 255}
+	for l := 0; l < fogLevels; l++ {
+		for i := 0; i < floorShades; i++ {
+			c := black
+			if i < len(reps) {
+				c = vga.LerpColor(src[reps[i]], black, fogAmount(l))
+			}
+			pal[l*floorShades+i] = c
+		}
+	}
+	return pal, texels
+}
+
+func (f *Floor) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(f.pal)
+}
+
+func (f *Floor) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	f.turn.update(dt, tempo, sync)
+	f.height.update(dt, tempo, sync)
+	f.horizon.update(dt, tempo, sync)
+
+	step := f.speed * tempo * dt
+	f.camX += math.Cos(f.turn.value) * step
+	f.camY += math.Sin(f.turn.value) * step
+}
+
+func (f *Floor) Draw(fb *vga.Framebuffer) {
+	f.horizonY = int(float64(fb.Height) * f.horizon.value)
+	f.proj = float64(fb.Width) / 2 // 90 degree field of view
+	f.sin, f.cos = math.Sincos(f.turn.value)
+	DrawParallel(fb, f)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (f *Floor) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	const one = 1 << 16
+	camH := math.Max(f.height.value, 1)
+	half := fb.Width / 2
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		dy := y - f.horizonY
+		if dy < 0 && f.ceiling {
+			dy = -dy // the ceiling is the floor mirrored about the horizon
+		}
+		if dy <= 0 {
+			for x := range row {
+				row[x] = f.sky
+			}
+			continue
+		}
+
+		// Distance to the plane along this scanline, then the texel step
+		// across it: perpendicular to the view direction, growing with z.
+		z := camH * f.proj / float64(dy)
+		level := int(z / f.fogDist * fogLevels)
+		if level >= fogLevels-1 {
+			for x := range row {
+				row[x] = f.sky
+			}
+			continue
+		}
+		shade := byte(level * floorShades)
+		du := int(-f.sin * z / f.proj * one)
+		dv := int(f.cos * z / f.proj * one)
+		u := int((f.camX+f.cos*z)*one) - half*du
+		v := int((f.camY+f.sin*z)*one) - half*dv
+		for x := range row {
+			tx := wrapTexel(u>>16, f.tex.Width)
+			ty := wrapTexel(v>>16, f.tex.Height)
+			row[x] = shade + f.texels[ty*f.tex.Width+tx]
+			u += du
+			v += dv
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Iteration limits for the adaptive budget.
+const (
+	minFractalIter = 32
+	maxFractalIter = 2048
+)
+
+// Fractal zooms into the Mandelbrot set or an animated Julia set. Escape
+// times are coloured smoothly (fractional iteration counts) into palette
+// entries 1-255, cycling with the music; points inside the set use entry 0.
+//
+// Deeper zooms need more iterations. Unless "iterations" fixes the count,
+// it follows the zoom depth but is capped by a budget that adapts to keep
+// the frame's render time under "budget" milliseconds. The cap depends on
+// wall-clock time, so fix "iterations" for reproducible offline renders.
+//
+// Cue params: "mode" ("mandelbrot" or "julia"), "x" and "y" (zoom target),
+// "zoom" (a number is a rate in doublings per second at 120 BPM, zooming
+// back out at "max_zoom"; keyframes give the depth in doublings), "c_angle"
+// and "c_radius" (Julia constant in polar form; a number angle is a rate),
+// "iterations", "budget", "density" (palette entries per iteration),
+// "cycle" (palette cycling speed) and "palette".
+type Fractal struct {
+	julia   bool
+	x, y    motion
+	zoom    motion
+	maxZoom float64
+	cAngle  motion
+	cRadius motion
+	fixed   int
+	budget  time.Duration
+	density float64
+	cycle   float64
+	pal     vga.Palette
+
+	zoomDir float64 // 1 zooming in, -1 out; for rate-driven zoom only
+	offset  float64 // palette cycling position
+	limit   float64 // iteration cap from the frame time budget
+
+	// Per-frame view, set by Draw.
+	iter           int
+	left, top, res float64 // top-left corner and units per pixel
+	cx, cy         float64 // Julia constant
+}
+
+func NewFractal() *Fractal {
+	f := &Fractal{}
+	f.Configure(nil)
+	return f
+}
+
+// Configure applies cue params; see Configurable.
+func (f *Fractal) Configure(p Params) error {
+	defX, defY := -0.7436438870371587, 0.1318259042053120 // Seahorse Valley
+	defZoom := 0.5
+	var julia bool
+	switch mode := p.String("mode", "mandelbrot"); mode {
+	case "mandelbrot":
+	case "julia":
+		julia = true
+		defX, defY, defZoom = 0, 0, 0 // the shape morphs instead
+	default:
+		return fmt.Errorf("mode: unknown mode %q", mode)
+	}
+	x, err := p.animated("x", defX)
+	if err != nil {
+		return err
+	}
+	y, err := p.animated("y", defY)
+	if err != nil {
+		return err
+	}
+	zoom, err := p.motion("zoom", defZoom)
+	if err != nil {
+		return err
+	}
+	// float64 runs out of precision a little beyond 2^45.
+	maxZoom := p.Float("max_zoom", 40)
+	if maxZoom <= 0 || maxZoom > 45 {
+		return fmt.Errorf("max_zoom: must be 0-45 doublings")
+	}
+	cAngle, err := p.motion("c_angle", 0.25)
+	if err != nil {
+		return err
+	}
+	cRadius, err := p.animated("c_radius", 0.7885)
+	if err != nil {
+		return err
+	}
+	fixed := p.Int("iterations", 0)
+	if fixed < 0 || fixed > maxFractalIter {
+		return fmt.Errorf("iterations: must be 0-%d", maxFractalIter)
+	}
+	pal, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Cyclic, so palette cycling has no seam.
+		pal = vga.RampPalette(
+			color.RGBA{0, 8, 48, 255}, color.RGBA{32, 96, 200, 255}, color.RGBA{240, 248, 255, 255},
+			color.RGBA{255, 176, 0, 255}, color.RGBA{96, 8, 40, 255}, color.RGBA{0, 8, 48, 255})
+	}
+	pal[0] = color.RGBA{0, 0, 0, 255}
+
+	f.julia = julia
+	f.x, f.y, f.zoom, f.maxZoom = x, y, zoom, maxZoom
+	f.cAngle, f.cRadius = cAngle, cRadius
+	f.fixed = fixed
+	f.budget = time.Duration(p.Float("budget", 10) * float64(time.Millisecond))
+	f.density = p.Float("density", 3)
+	f.cycle = p.Float("cycle", 8)
+	f.pal = pal
+
+	f.zoomDir = 1
+	f.limit = maxFractalIter / 4
+	return nil
+}
+
+func (f *Fractal) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(f.pal)
+}
+
+func (f *Fractal) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	f.x.update(dt, tempo, sync)
+	f.y.update(dt, tempo, sync)
+	f.cAngle.update(dt, tempo, sync)
+	f.cRadius.update(dt, tempo, sync)
+	if f.zoom.track != nil {
+		f.zoom.update(dt, tempo, sync)
+	} else {
+		// Ping-pong between the full view and the deepest zoom.
+		f.zoom.value += f.zoom.rate * f.zoomDir * dt * tempo
+		if f.zoom.value >= f.maxZoom {
+			f.zoom.value, f.zoomDir = f.maxZoom, -1
+		} else if f.zoom.value <= 0 {
+			f.zoom.value, f.zoomDir = 0, 1
+		}
+	}
+	f.zoom.value = math.Min(math.Max(f.zoom.value, 0), f.maxZoom)
+	f.offset += dt * tempo * f.cycle * (1 + 2*sync.BeatPulse())
+}
+
+func (f *Fractal) Draw(fb *vga.Framebuffer) {
+	// Detail needs roughly linear iterations in the zoom depth.
+	f.iter = f.fixed
+	if f.iter == 0 {
+		f.iter = min(64+int(24*f.zoom.value), int(f.limit))
+	}
+
+	w, h := float64(fb.Width), float64(fb.Height)
+	f.res = 3.5 / w / math.Exp2(f.zoom.value)
+	f.left = f.x.value - w/2*f.res
+	f.top = f.y.value - h/2*f.res
+	f.cy, f.cx = math.Sincos(f.cAngle.value)
+	f.cx *= f.cRadius.value
+	f.cy *= f.cRadius.value
+
+	start := time.Now()
+	DrawParallel(fb, f)
+	if f.fixed == 0 {
+		// Scale the cap by how far off budget the frame was, gently.
+		ratio := float64(f.budget) / float64(max(time.Since(start), time.Microsecond))
+		f.limit *= math.Min(math.Max(ratio, 0.8), 1.1)
+		f.limit = math.Min(math.Max(f.limit, minFractalIter), maxFractalIter)
+	}
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (f *Fractal) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	const bailout = 256 // a large radius makes the smooth colouring smoother
+	invLn2 := 1 / math.Ln2
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		py := f.top + float64(y)*f.res
+		for x := range row {
+			px := f.left + float64(x)*f.res
+			zx, zy, cx, cy := px, py, px, py
+			if f.julia {
+				cx, cy = f.cx, f.cy
+			} else if inMainBulbs(px, py) {
+				row[x] = 0
+				continue
+			}
+
+			i := 0
+			x2, y2 := zx*zx, zy*zy
+			for ; i < f.iter && x2+y2 <= bailout; i++ {
+				zy = 2*zx*zy + cy
+				zx = x2 - y2 + cx
+				x2, y2 = zx*zx, zy*zy
+			}
+			if i == f.iter {
+				row[x] = 0
+				continue
+			}
+			// Fractional escape count: log2(log|z|) falls by one per iteration.
+			nu := float64(i) + 1 - math.Log(0.5*math.Log(x2+y2))*invLn2
+			c := int(nu*f.density+f.offset) % 255
+			if c < 0 {
+				c += 255
+			}
+			row[x] = byte(1 + c)
+		}
+	}
+}
+
+// inMainBulbs reports whether c lies in the Mandelbrot set's main cardioid
+// or period-2 bulb, which would otherwise always run to the iteration limit.
+func inMainBulbs(x, y float64) bool {
+	q := (x-0.25)*(x-0.25) + y*y
+	if q*(q+x-0.25) <= 0.25*y*y {
+		return true
+	}
+	return (x+1)*(x+1)+y*y <= 1.0/16
+}
@@ -0,0 +1,154 @@
+package effects
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/holden/vga-go/internal/music"
+)
+
+// Key is a keyframe: Value at tracker position Order:Row. Ease is how the
+// value moves on to the next key: "linear" (the default), "smooth" (eased
+// in and out) or "step" (hold until the next key).
+type Key struct {
+	Order int
+	Row   int
+	Value float64
+	Ease  string
+}
+
+// Track is a list of keyframes sorted by position, addressed by tracker
+// position like cues are.
+type Track []Key
+
+// rowsPerOrder converts order:row positions to a row count for
+// interpolating between keys in different orders. Patterns are assumed to
+// be the current pattern's length, 64 rows if unknown.
+func rowsPerOrder(info music.FrameInfo) float64 {
+	if info.NumRows > 0 {
+		return float64(info.NumRows)
+	}
+	return 64
+}
+
+// At returns the track's value at the current playback position. Before the
+// first key it holds the first value, after the last key the last value. An
+// empty track returns 0.
+func (t Track) At(info music.FrameInfo) float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	n := rowsPerOrder(info)
+	pos := float64(info.Order)*n + float64(info.Row)
+	if info.Speed > 0 {
+		pos += float64(info.Frame) / float64(info.Speed)
+	}
+	keyPos := func(k Key) float64 { return float64(k.Order)*n + float64(k.Row) }
+
+	i, _ := slices.BinarySearchFunc(t, pos, func(k Key, p float64) int {
+		if kp := keyPos(k); kp <= p {
+			return -1
+		}
+		return 1
+	})
+	// t[i-1] is the last key at or before pos.
+	if i == 0 {
+		return t[0].Value
+	}
+	if i == len(t) {
+		return t[i-1].Value
+	}
+	a, b := t[i-1], t[i]
+	span := keyPos(b) - keyPos(a)
+	if span <= 0 {
+		return b.Value
+	}
+	f := (pos - keyPos(a)) / span
+	switch a.Ease {
+	case "step":
+		f = 0
+	case "smooth":
+		f = f * f * (3 - 2*f)
+	}
+	return a.Value + f*(b.Value-a.Value)
+}
+
+// Track reads a keyframe list:
+//
+//	[{"order": 0, "row": 0, "value": 0}, {"order": 2, "row": 0, "value": 6.28, "ease": "smooth"}]
+//
+// Keys are sorted by position. ok is false if the key is missing.
+func (p Params) Track(key string) (t Track, ok bool, err error) {
+	v, present := p[key]
+	if !present {
+		return nil, false, nil
+	}
+	list, isList := v.([]any)
+	if !isList {
+		return nil, false, fmt.Errorf("%s: keyframes must be a list", key)
+	}
+	for i, item := range list {
+		obj, isObj := item.(map[string]any)
+		if !isObj {
+			return nil, false, fmt.Errorf("%s[%d]: keyframe must be an object", key, i)
+		}
+		kp := Params(obj)
+		if _, has := kp["value"].(float64); !has {
+			return nil, false, fmt.Errorf("%s[%d]: keyframe needs a numeric value", key, i)
+		}
+		ease := kp.String("ease", "linear")
+		switch ease {
+		case "linear", "smooth", "step":
+		default:
+			return nil, false, fmt.Errorf("%s[%d]: unknown ease %q", key, i, ease)
+		}
+		t = append(t, Key{Order: kp.Int("order", 0), Row: kp.Int("row", 0), Value: kp.Float("value", 0), Ease: ease})
+	}
+	slices.SortStableFunc(t, func(a, b Key) int {
+		if a.Order != b.Order {
+			return a.Order - b.Order
+		}
+		return a.Row - b.Row
+	})
+	return t, true, nil
+}
+
+// motion is a value driven either by a keyframe track (absolute values) or
+// by a rate integrated over time, scaled by tempo.
+type motion struct {
+	track Track
+	rate  float64
+	value float64
+}
+
+func (m *motion) update(dt, speed float64, info music.FrameInfo) {
+	if m.track != nil {
+		m.value = m.track.At(info)
+		return
+	}
+	m.value += m.rate * dt * speed
+}
+
+// motion reads a motion: a number is a rate (def if missing), a list is
+// keyframes.
+func (p Params) motion(key string, def float64) (motion, error) {
+	switch p[key].(type) {
+	case nil, float64:
+		return motion{rate: p.Float(key, def)}, nil
+	}
+	t, _, err := p.Track(key)
+	if err != nil {
+		return motion{}, err
+	}
+	return motion{track: t}, nil
+}
+
+// animated reads a value that is either constant (a number, def if
+// missing) or keyframed.
+func (p Params) animated(key string, def float64) (motion, error) {
+	m, err := p.motion(key, def)
+	if m.track == nil {
+		m.value, m.rate = m.rate, 0
+	}
+	return m, err
+}
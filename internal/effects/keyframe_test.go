@@ -0,0 +1,89 @@
+package effects
+
+import (
+	"math"
+	"testing"
+
+	"github.com/holden/vga-go/internal/music"
+)
+
+func TestTrackAt(t *testing.T) {
+	track := Track{
+		{Order: 0, Row: 0, Value: 0},
+		{Order: 0, Row: 16, Value: 10, Ease: "smooth"},
+		{Order: 0, Row: 32, Value: 20, Ease: "step"},
+		{Order: 1, Row: 0, Value: 30},
+	}
+	tests := []struct {
+		name string
+		info music.FrameInfo
+		want float64
+	}{
+		{"first key", music.FrameInfo{Row: 0, NumRows: 64}, 0},
+		{"linear halfway", music.FrameInfo{Row: 8, NumRows: 64}, 5},
+		{"between rows", music.FrameInfo{Row: 8, Frame: 3, Speed: 6, NumRows: 64}, 5.3125},
+		{"at a key", music.FrameInfo{Row: 16, NumRows: 64}, 10},
+		{"smooth quarter", music.FrameInfo{Row: 20, NumRows: 64}, 11.5625}, // 10 + 10 * 0.15625
+		{"smooth halfway", music.FrameInfo{Row: 24, NumRows: 64}, 15},
+		{"step holds", music.FrameInfo{Row: 63, NumRows: 64}, 20},
+		{"next order", music.FrameInfo{Order: 1, Row: 0, NumRows: 64}, 30},
+		{"after the last key", music.FrameInfo{Order: 5, Row: 10, NumRows: 64}, 30},
+		{"unknown pattern length", music.FrameInfo{Row: 40}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := track.At(tt.info); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("At = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Keys in different orders are interpolated over the current pattern length.
+func TestTrackAtAcrossOrders(t *testing.T) {
+	track := Track{{Order: 0, Row: 16, Value: 0}, {Order: 1, Row: 16, Value: 1}}
+	for _, tt := range []struct {
+		info music.FrameInfo
+		want float64
+	}{
+		{music.FrameInfo{Order: 0, Row: 0, NumRows: 32}, 0}, // before the first key
+		{music.FrameInfo{Order: 1, Row: 0, NumRows: 32}, 0.5},
+		{music.FrameInfo{Order: 1, Row: 0, NumRows: 64}, 0.75},
+	} {
+		if got := track.At(tt.info); got != tt.want {
+			t.Errorf("%d:%d of %d rows = %v, want %v", tt.info.Order, tt.info.Row, tt.info.NumRows, got, tt.want)
+		}
+	}
+	if got := (Track{}).At(music.FrameInfo{}); got != 0 {
+		t.Errorf("empty track = %v, want 0", got)
+	}
+}
+
+func TestParamsTrack(t *testing.T) {
+	p := Params{"angle": []any{
+		map[string]any{"order": 2.0, "value": 6.0, "ease": "smooth"},
+		map[string]any{"order": 0.0, "row": 8.0, "value": 1.0},
+	}}
+	track, ok, err := p.Track("angle")
+	if err != nil || !ok {
+		t.Fatalf("Track: ok %v, err %v", ok, err)
+	}
+	want := Track{{Order: 0, Row: 8, Value: 1, Ease: "linear"}, {Order: 2, Row: 0, Value: 6, Ease: "smooth"}}
+	if len(track) != len(want) || track[0] != want[0] || track[1] != want[1] {
+		t.Errorf("Track = %v, want %v", track, want)
+	}
+
+	for name, v := range map[string]any{
+		"not a list":    1.0,
+		"not an object": []any{1.0},
+		"no value":      []any{map[string]any{"order": 1.0}},
+		"unknown ease":  []any{map[string]any{"value": 1.0, "ease": "bounce"}},
+	} {
+		if _, _, err := (Params{"angle": v}).Track("angle"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if _, ok, err := (Params{}).Track("angle"); ok || err != nil {
+		t.Errorf("missing key: ok %v, err %v", ok, err)
+	}
+}
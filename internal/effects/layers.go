@@ -0,0 +1,104 @@
+package effects
+
+import (
+	"fmt"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Overlay is implemented by effects meant to be drawn on top of another
+// effect. Such effects draw colour 0 wherever they are transparent and keep
+// their colours in a range of the palette; InitOverlay sets only that
+// range, leaving the rest to the effect underneath.
+type Overlay interface {
+	InitOverlay(fb *vga.Framebuffer)
+}
+
+// Layers composes a base effect with overlays drawn on top of it, colour 0
+// being transparent in each overlay. Any effect can be an overlay; those
+// implementing Overlay also share the palette cleanly. An overlay's copper
+// list is passed on to the screen, replacing any the layers below set.
+//
+// Cue params: "base" (params object for the base effect) and "overlays"
+// (list of params objects, one per overlay).
+type Layers struct {
+	base     Effect
+	overlays []Effect
+	scratch  *vga.Framebuffer
+}
+
+func NewLayers(base Effect, overlays ...Effect) *Layers {
+	return &Layers{base: base, overlays: overlays}
+}
+
+// Configure passes nested params on to the layers; see Configurable.
+func (l *Layers) Configure(p Params) error {
+	sub, _ := p["base"].(map[string]any)
+	if err := configureLayer(l.base, sub); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+	list, _ := p["overlays"].([]any)
+	for i, o := range l.overlays {
+		var sub map[string]any
+		if i < len(list) {
+			sub, _ = list[i].(map[string]any)
+		}
+		if err := configureLayer(o, sub); err != nil {
+			return fmt.Errorf("overlays[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func configureLayer(e Effect, p Params) error {
+	c, ok := e.(Configurable)
+	if !ok {
+		if p != nil {
+			return fmt.Errorf("effect does not take params")
+		}
+		return nil
+	}
+	return c.Configure(p)
+}
+
+func (l *Layers) Init(fb *vga.Framebuffer) {
+	l.base.Init(fb)
+	for _, o := range l.overlays {
+		if ov, ok := o.(Overlay); ok {
+			ov.InitOverlay(fb)
+		} else {
+			o.Init(fb)
+		}
+	}
+}
+
+func (l *Layers) Update(dt float64, sync music.FrameInfo) {
+	l.base.Update(dt, sync)
+	for _, o := range l.overlays {
+		o.Update(dt, sync)
+	}
+}
+
+func (l *Layers) Draw(fb *vga.Framebuffer) {
+	l.base.Draw(fb)
+	if len(l.overlays) == 0 {
+		return
+	}
+	if l.scratch == nil || l.scratch.Width != fb.Width || l.scratch.Height != fb.Height {
+		mode := fb.Mode
+		mode.Width, mode.Height = fb.Width, fb.Height
+		l.scratch = vga.NewFramebufferMode(mode, fb.Palette)
+	}
+	for _, o := range l.overlays {
+		l.scratch.Palette = fb.Palette
+		l.scratch.Clear(0)
+		o.Draw(l.scratch)
+		fb.CopyFromKeyed(l.scratch, 0)
+		// Copper lists belong to the screen, not the layer's pixels.
+		if c := l.scratch.Copper(); c != nil {
+			fb.SetCopper(c)
+			l.scratch.ResetRaster()
+		}
+	}
+}
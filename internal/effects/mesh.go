@@ -0,0 +1,258 @@
+package effects
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Face is a convex polygon of vertex indices, wound counter-clockwise when
+// seen from outside the mesh. Ramp selects which palette ramp shades it.
+type Face struct {
+	V    []int
+	Ramp int
+}
+
+// Mesh is a polygon mesh for the vector effect.
+type Mesh struct {
+	Verts []Vec3
+	Faces []Face
+
+	faceNormals []Vec3
+	vertNormals []Vec3
+}
+
+// NewCube returns a cube scaled to unit radius, one ramp per opposite face
+// pair.
+func NewCube() *Mesh {
+	m := &Mesh{}
+	for i := 0; i < 8; i++ {
+		m.Verts = append(m.Verts, Vec3{float64(i&1*2 - 1), float64(i>>1&1*2 - 1), float64(i>>2&1*2 - 1)})
+	}
+	quads := [6][4]int{
+		{0, 1, 3, 2}, {4, 6, 7, 5}, // -Z, +Z
+		{0, 4, 5, 1}, {2, 3, 7, 6}, // -Y, +Y
+		{0, 2, 6, 4}, {1, 5, 7, 3}, // -X, +X
+	}
+	for i, q := range quads {
+		m.Faces = append(m.Faces, Face{V: q[:], Ramp: i / 2})
+	}
+	m.orientOutward(func(Face) Vec3 { return Vec3{} })
+	m.normalizeSize()
+	m.computeNormals()
+	return m
+}
+
+// NewTorus returns a torus around the Y axis with major radius r0 and tube
+// radius r1 (relative sizes; the result is scaled to unit radius), built
+// from segs x sides quads. Ramps alternate in a checker.
+func NewTorus(r0, r1 float64, segs, sides int) *Mesh {
+	segs, sides = max(segs, 3), max(sides, 3)
+	m := &Mesh{}
+	for i := 0; i < segs; i++ {
+		su, cu := math.Sincos(2 * math.Pi * float64(i) / float64(segs))
+		for j := 0; j < sides; j++ {
+			sv, cv := math.Sincos(2 * math.Pi * float64(j) / float64(sides))
+			m.Verts = append(m.Verts, Vec3{(r0 + r1*cv) * cu, r1 * sv, (r0 + r1*cv) * su})
+		}
+	}
+	idx := func(i, j int) int { return (i%segs)*sides + j%sides }
+	for i := 0; i < segs; i++ {
+		for j := 0; j < sides; j++ {
+			m.Faces = append(m.Faces, Face{
+				V:    []int{idx(i, j), idx(i+1, j), idx(i+1, j+1), idx(i, j+1)},
+				Ramp: (i + j) & 1,
+			})
+		}
+	}
+	// Outward is away from the tube's centre circle.
+	m.orientOutward(func(f Face) Vec3 {
+		c := m.centroid(f)
+		return Vec3{c.X, 0, c.Z}.Normalize().Scale(r0)
+	})
+	m.normalizeSize()
+	m.computeNormals()
+	return m
+}
+
+// NewIcosphere returns a unit sphere made by subdividing an icosahedron
+// subdiv times (0 gives the 20-face icosahedron).
+func NewIcosphere(subdiv int) *Mesh {
+	t := (1 + math.Sqrt(5)) / 2
+	m := &Mesh{Verts: []Vec3{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}}
+	tris := [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+	for i := range m.Verts {
+		m.Verts[i] = m.Verts[i].Normalize()
+	}
+	for s := 0; s < subdiv; s++ {
+		mid := make(map[[2]int]int)
+		midpoint := func(a, b int) int {
+			k := [2]int{min(a, b), max(a, b)}
+			if i, ok := mid[k]; ok {
+				return i
+			}
+			m.Verts = append(m.Verts, m.Verts[a].Add(m.Verts[b]).Normalize())
+			mid[k] = len(m.Verts) - 1
+			return mid[k]
+		}
+		next := make([][3]int, 0, len(tris)*4)
+		for _, tr := range tris {
+			a, b, c := midpoint(tr[0], tr[1]), midpoint(tr[1], tr[2]), midpoint(tr[2], tr[0])
+			next = append(next, [3]int{tr[0], a, c}, [3]int{tr[1], b, a}, [3]int{tr[2], c, b}, [3]int{a, b, c})
+		}
+		tris = next
+	}
+	for i, tr := range tris {
+		m.Faces = append(m.Faces, Face{V: []int{tr[0], tr[1], tr[2]}, Ramp: i & 1})
+	}
+	m.orientOutward(func(Face) Vec3 { return Vec3{} })
+	m.computeNormals()
+	return m
+}
+
+// LoadOBJ reads the vertices and faces of a Wavefront OBJ file. Texture and
+// normal indices are ignored (normals are recomputed); each "usemtl" switches
+// to the next palette ramp. The mesh is centred and scaled to unit radius.
+func LoadOBJ(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Mesh{}
+	ramp, materials := 0, 0
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("%s:%d: vertex needs 3 coordinates", path, line)
+			}
+			var c [3]float64
+			for i := range c {
+				if c[i], err = strconv.ParseFloat(fields[i+1], 64); err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+				}
+			}
+			m.Verts = append(m.Verts, Vec3{c[0], c[1], c[2]})
+		case "f":
+			face := Face{Ramp: ramp}
+			for _, ref := range fields[1:] {
+				idx, err := strconv.Atoi(strings.SplitN(ref, "/", 2)[0])
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+				}
+				if idx < 0 {
+					idx += len(m.Verts) + 1
+				}
+				if idx < 1 || idx > len(m.Verts) {
+					return nil, fmt.Errorf("%s:%d: vertex %d out of range", path, line, idx)
+				}
+				face.V = append(face.V, idx-1)
+			}
+			if len(face.V) >= 3 {
+				m.Faces = append(m.Faces, face)
+			}
+		case "usemtl":
+			ramp = materials % numRamps
+			materials++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(m.Faces) == 0 {
+		return nil, fmt.Errorf("%s: no faces", path)
+	}
+	m.normalizeSize()
+	m.computeNormals()
+	return m, nil
+}
+
+// normalizeSize centres the mesh on its bounding box and scales it to fit
+// a unit sphere.
+func (m *Mesh) normalizeSize() {
+	lo, hi := m.Verts[0], m.Verts[0]
+	for _, v := range m.Verts {
+		lo = Vec3{math.Min(lo.X, v.X), math.Min(lo.Y, v.Y), math.Min(lo.Z, v.Z)}
+		hi = Vec3{math.Max(hi.X, v.X), math.Max(hi.Y, v.Y), math.Max(hi.Z, v.Z)}
+	}
+	c := lo.Add(hi).Scale(0.5)
+	r := 0.0
+	for i := range m.Verts {
+		m.Verts[i] = m.Verts[i].Sub(c)
+		r = math.Max(r, m.Verts[i].Len())
+	}
+	if r > 0 {
+		for i := range m.Verts {
+			m.Verts[i] = m.Verts[i].Scale(1 / r)
+		}
+	}
+}
+
+func (m *Mesh) centroid(f Face) Vec3 {
+	var c Vec3
+	for _, i := range f.V {
+		c = c.Add(m.Verts[i])
+	}
+	return c.Scale(1 / float64(len(f.V)))
+}
+
+// faceNormal uses Newell's method, which also copes with slightly
+// non-planar polygons.
+func (m *Mesh) faceNormal(f Face) Vec3 {
+	var n Vec3
+	for k, i := range f.V {
+		a, b := m.Verts[i], m.Verts[f.V[(k+1)%len(f.V)]]
+		n.X += (a.Y - b.Y) * (a.Z + b.Z)
+		n.Y += (a.Z - b.Z) * (a.X + b.X)
+		n.Z += (a.X - b.X) * (a.Y + b.Y)
+	}
+	return n.Normalize()
+}
+
+// orientOutward reverses faces whose normal points towards inside(f), a
+// point known to be inside the mesh behind that face.
+func (m *Mesh) orientOutward(inside func(Face) Vec3) {
+	for _, f := range m.Faces {
+		if m.faceNormal(f).Dot(m.centroid(f).Sub(inside(f))) < 0 {
+			for i, j := 0, len(f.V)-1; i < j; i, j = i+1, j-1 {
+				f.V[i], f.V[j] = f.V[j], f.V[i]
+			}
+		}
+	}
+}
+
+// computeNormals caches face normals and smooth per-vertex normals (the
+// average of adjoining faces) for Gouraud shading.
+func (m *Mesh) computeNormals() {
+	m.faceNormals = make([]Vec3, len(m.Faces))
+	m.vertNormals = make([]Vec3, len(m.Verts))
+	for i, f := range m.Faces {
+		n := m.faceNormal(f)
+		m.faceNormals[i] = n
+		for _, v := range f.V {
+			m.vertNormals[v] = m.vertNormals[v].Add(n)
+		}
+	}
+	for i := range m.vertNormals {
+		m.vertNormals[i] = m.vertNormals[i].Normalize()
+	}
+}
@@ -0,0 +1,180 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Falloff table: field contribution by squared distance relative to the
+// ball's radius, q = (d/r)^2, sampled at falloffScale steps per unit of q.
+// Contributions reach zero at q = falloffReach (twice the radius).
+const (
+	falloffScale = 256
+	falloffReach = 4
+	falloffSize  = falloffScale * falloffReach
+	fieldOne     = 1 << 12 // field value on the surface (a lone ball at its radius)
+	maxBalls     = 16
+)
+
+// falloff holds the kernel (1 - q/reach)^3, scaled so that a lone ball's
+// field equals fieldOne exactly at its radius (q = 1).
+var falloff [falloffSize]int32
+
+func init() {
+	atRadius := math.Pow(1-1.0/falloffReach, 3)
+	for i := range falloff {
+		q := float64(i) / falloffScale
+		falloff[i] = int32(math.Pow(1-q/falloffReach, 3) / atRadius * fieldOne)
+	}
+}
+
+// ball is a metaball's screen position and precomputed 1/r^2 scale for the
+// falloff table index, in 16.16 fixed point.
+type ball struct {
+	x, y int
+	inv  int64
+}
+
+// Metaballs is an implicit-surface blob effect: moving field sources whose
+// summed falloff is thresholded into palette bands. Each ball's radius pumps
+// with the volume of one tracker channel. Integer field maths and
+// dt-driven motion keep offline renders deterministic.
+//
+// Cue params: "balls" (count, up to 16), "bands" (colour bands inside the
+// surface), "glow" (soft halo outside it), "pump" (how strongly volume
+// grows the radius) and "palette" (band colours are sampled from it).
+type Metaballs struct {
+	n     int
+	bands int
+	glow  bool
+	pump  float64
+	pal   vga.Palette
+
+	time  float64
+	vol   [maxBalls]float64
+	balls [maxBalls]ball
+}
+
+func NewMetaballs() *Metaballs {
+	m := &Metaballs{}
+	m.Configure(nil)
+	return m
+}
+
+// Configure applies cue params; see Configurable.
+func (m *Metaballs) Configure(p Params) error {
+	n := p.Int("balls", 6)
+	if n < 1 || n > maxBalls {
+		return fmt.Errorf("balls: must be 1-%d", maxBalls)
+	}
+	src, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		src = vga.RampPalette(
+			color.RGBA{32, 0, 96, 255}, color.RGBA{224, 32, 160, 255},
+			color.RGBA{255, 192, 64, 255}, color.RGBA{255, 255, 255, 255})
+	}
+
+	m.n = n
+	m.bands = max(p.Int("bands", 4), 1)
+	m.glow = p.Bool("glow", true)
+	m.pump = p.Float("pump", 0.6)
+	m.pal = metaballPalette(src, m.bands, m.glow)
+	return nil
+}
+
+// metaballPalette maps field values to colours: indices below 128 are
+// outside the surface (black, or a dim halo ramping up to the edge with
+// glow), 128 and up are inside, in bands sampled from src.
+func metaballPalette(src vga.Palette, bands int, glow bool) vga.Palette {
+	var pal vga.Palette
+	edge := src[0]
+	for i := 0; i < 128; i++ {
+		pal[i] = color.RGBA{0, 0, 0, 255}
+		if glow {
+			t := float64(i) / 127
+			t = t * t * t // stays dark until close to the surface
+			pal[i] = color.RGBA{uint8(float64(edge.R) * t), uint8(float64(edge.G) * t), uint8(float64(edge.B) * t), 255}
+		}
+	}
+	for i := 128; i < 256; i++ {
+		band := (i - 128) * bands / 128
+		idx := 255
+		if bands > 1 {
+			idx = band * 255 / (bands - 1)
+		}
+		pal[i] = src[idx]
+	}
+	return pal
+}
+
+func (m *Metaballs) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(m.pal)
+}
+
+func (m *Metaballs) Update(dt float64, sync music.FrameInfo) {
+	speed := 1.0
+	if sync.BPM > 0 {
+		speed = float64(sync.BPM) / 120.0
+	}
+	m.time += dt * speed
+
+	// Volumes jump up with the channel and decay smoothly.
+	decay := math.Pow(0.02, dt)
+	for i := 0; i < m.n; i++ {
+		v := 0.0
+		if sync.NumChannels > 0 {
+			v = float64(sync.ChannelVol[i%sync.NumChannels]) / 255
+		}
+		m.vol[i] = math.Max(m.vol[i]*decay, v)
+	}
+}
+
+func (m *Metaballs) Draw(fb *vga.Framebuffer) {
+	w, h := float64(fb.Width), float64(fb.Height)
+	base := h * 0.11
+	for i := 0; i < m.n; i++ {
+		// Lissajous paths with per-ball frequencies and phases.
+		fi := float64(i)
+		x := 0.5 + 0.38*math.Sin(m.time*(0.37+0.11*fi)+fi*1.7)
+		y := 0.5 + 0.36*math.Cos(m.time*(0.29+0.07*fi)+fi*2.3)
+		r := base * (1 + m.pump*m.vol[i])
+		m.balls[i] = ball{
+			x:   int(x * w),
+			y:   int(y * h),
+			inv: int64(falloffScale / (r * r) * (1 << 16)),
+		}
+	}
+	DrawParallel(fb, m)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (m *Metaballs) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	balls := m.balls[:m.n]
+	var dy2 [maxBalls]int64
+	for y := y0; y < y1; y++ {
+		for i := range balls {
+			dy := int64(y - balls[i].y)
+			dy2[i] = dy * dy
+		}
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		for x := range row {
+			var field int32
+			for i := range balls {
+				dx := int64(x - balls[i].x)
+				q := ((dx*dx + dy2[i]) * balls[i].inv) >> 16
+				if q < falloffSize {
+					field += falloff[q]
+				}
+			}
+			// The surface (field = fieldOne) lands on index 128.
+			row[x] = byte(min(field*128/fieldOne, 255))
+		}
+	}
+}
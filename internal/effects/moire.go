@@ -0,0 +1,182 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+const maxGenerators = 4
+
+// Moire overlays moving pattern generators and combines them into palette
+// bands: concentric rings ("circles", the classic interference circles) or
+// fans of rays ("lines"). Ring spacing shrinks on each beat.
+//
+// Every generator reads the same precomputed table, twice the screen size
+// in each direction and centred on its middle, through a window offset to
+// the generator's position, so the per-pixel work is a lookup and a
+// multiply per generator.
+//
+// Cue params: "mode" ("circles" or "lines"), "generators" (2-4),
+// "combine" ("xor" or "add"), "bands" (colours, 2 or more), "spacing"
+// (pixels between rings at 320 wide), "rays" (lines per fan), "pulse"
+// (how much spacing shrinks on the beat), "speed" and "palette".
+type Moire struct {
+	lines   bool
+	n       int
+	xor     bool
+	bands   int
+	spacing float64
+	rays    int
+	pulse   float64
+	speed   float64
+	pal     vga.Palette
+
+	time float64
+	beat float64
+
+	// Distance (in 1/16 pixels) or angle (a full turn is 65536) from the
+	// table's centre, rebuilt when the size or mode changes.
+	w, h   int
+	tw     int // table width, 2*w
+	table  []uint16
+	tLines bool
+
+	// Per-frame generator state, set by Draw.
+	offs [maxGenerators]int    // window offset of each generator in table
+	turn [maxGenerators]uint16 // lines: rotation of each fan
+	mul  int                   // table value to ring or ray count, 16.16
+}
+
+func NewMoire() *Moire {
+	m := &Moire{}
+	m.Configure(nil)
+	return m
+}
+
+// Configure applies cue params; see Configurable.
+func (m *Moire) Configure(p Params) error {
+	var lines, xor bool
+	switch mode := p.String("mode", "circles"); mode {
+	case "circles", "lines":
+		lines = mode == "lines"
+	default:
+		return fmt.Errorf("mode: unknown mode %q", mode)
+	}
+	n := p.Int("generators", 2)
+	if n < 2 || n > maxGenerators {
+		return fmt.Errorf("generators: must be 2-%d", maxGenerators)
+	}
+	switch combine := p.String("combine", "xor"); combine {
+	case "xor", "add":
+		xor = combine == "xor"
+	default:
+		return fmt.Errorf("combine: unknown mode %q", combine)
+	}
+	bands := p.Int("bands", 2)
+	if bands < 2 || bands > 256 {
+		return fmt.Errorf("bands: must be 2-256")
+	}
+	src, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		src = vga.RampPalette(color.RGBA{0, 0, 0, 255}, color.RGBA{0, 96, 160, 255}, color.RGBA{240, 255, 255, 255})
+	}
+
+	m.lines, m.n, m.xor, m.bands = lines, n, xor, bands
+	m.spacing = math.Max(p.Float("spacing", 8), 1)
+	m.rays = max(p.Int("rays", 48), 1)
+	m.pulse = p.Float("pulse", 0.25)
+	m.speed = p.Float("speed", 1)
+	m.pal = blackPalette()
+	for i := 0; i < m.bands; i++ {
+		m.pal[i] = src[i*255/(m.bands-1)]
+	}
+	return nil
+}
+
+func (m *Moire) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(m.pal)
+}
+
+func (m *Moire) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	m.time += dt * tempo * m.speed
+	m.beat = sync.BeatPulse()
+}
+
+// buildTable computes the distance or angle table for a w x h screen.
+func (m *Moire) buildTable(w, h int) {
+	if m.w == w && m.h == h && m.tLines == m.lines {
+		return
+	}
+	m.w, m.h, m.tw, m.tLines = w, h, 2*w, m.lines
+	m.table = make([]uint16, 4*w*h)
+	for y := 0; y < 2*h; y++ {
+		for x := 0; x < 2*w; x++ {
+			dx, dy := float64(x-w), float64(y-h)
+			if m.lines {
+				a := math.Atan2(dy, dx) / (2 * math.Pi) // -0.5 to 0.5
+				m.table[y*m.tw+x] = uint16(int(a * 65536))
+			} else {
+				m.table[y*m.tw+x] = uint16(min(math.Sqrt(dx*dx+dy*dy)*16, math.MaxUint16))
+			}
+		}
+	}
+}
+
+func (m *Moire) Draw(fb *vga.Framebuffer) {
+	m.buildTable(fb.Width, fb.Height)
+	w, h := float64(m.w), float64(m.h)
+	for i := 0; i < m.n; i++ {
+		// Lissajous paths kept on screen, so windows stay inside the table.
+		fi := float64(i)
+		gx := int(w * (0.5 + 0.4*math.Sin(m.time*(0.5+0.17*fi)+fi*2.1)))
+		gy := int(h * (0.5 + 0.4*math.Sin(m.time*(0.37+0.11*fi)+fi*1.3)))
+		m.offs[i] = (m.h-gy)*m.tw + (m.w - gx)
+		m.turn[i] = uint16(int(m.time * 4000 * (fi - 0.5)))
+	}
+	if m.lines {
+		m.mul = m.rays
+	} else {
+		// Rings per 1/16 pixel of distance, in 16.16.
+		spacing := m.spacing * w / 320 * (1 - m.pulse*m.beat)
+		m.mul = int(65536 / (16 * math.Max(spacing, 0.5)))
+	}
+	DrawParallel(fb, m)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (m *Moire) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	offs := m.offs[:m.n]
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+m.w]
+		base := y * m.tw
+		for x := range row {
+			v := 0
+			for i, off := range offs {
+				t := m.table[base+x+off]
+				var band int
+				if m.lines {
+					band = int(t+m.turn[i]) * m.mul >> 16
+				} else {
+					band = int(t) * m.mul >> 16
+				}
+				if m.xor {
+					v ^= band
+				} else {
+					v += band
+				}
+			}
+			row[x] = byte(v % m.bands)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package effects
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// RowDrawer is implemented by effects whose scanlines can be rendered
+// independently of each other. DrawRows fills rows [y0, y1) of fb and may be
+// called concurrently for disjoint row ranges, so it must only read shared
+// effect state (prepare per-frame values before calling DrawParallel).
+type RowDrawer interface {
+	DrawRows(fb *vga.Framebuffer, y0, y1 int)
+}
+
+// minBandRows keeps bands from getting so thin that scheduling costs more
+// than the rows themselves.
+const minBandRows = 8
+
+type band struct {
+	d      RowDrawer
+	fb     *vga.Framebuffer
+	y0, y1 int
+	wg     *sync.WaitGroup
+}
+
+// bandPool is a fixed set of workers shared by all effects, started on first use.
+var bandPool struct {
+	once sync.Once
+	jobs chan band
+	n    int
+}
+
+func startBandPool() {
+	bandPool.n = runtime.GOMAXPROCS(0)
+	bandPool.jobs = make(chan band)
+	for i := 0; i < bandPool.n; i++ {
+		go func() {
+			for b := range bandPool.jobs {
+				b.d.DrawRows(b.fb, b.y0, b.y1)
+				b.wg.Done()
+			}
+		}()
+	}
+}
+
+// DrawParallel renders fb as horizontal bands split across the worker pool
+// and returns when every row is drawn. The calling goroutine renders bands
+// too, and takes over any band no worker is free for, so nested or
+// concurrent calls never stall.
+func DrawParallel(fb *vga.Framebuffer, d RowDrawer) {
+	bandPool.once.Do(startBandPool)
+	if bandPool.n < 2 || fb.Height <= minBandRows {
+		d.DrawRows(fb, 0, fb.Height)
+		return
+	}
+	// A few bands per worker evens out rows of uneven cost.
+	rows := max(minBandRows, (fb.Height+bandPool.n*4-1)/(bandPool.n*4))
+
+	var wg sync.WaitGroup
+	for y0 := 0; y0 < fb.Height; y0 += rows {
+		b := band{d: d, fb: fb, y0: y0, y1: min(y0+rows, fb.Height), wg: &wg}
+		wg.Add(1)
+		select {
+		case bandPool.jobs <- b:
+		default:
+			d.DrawRows(fb, b.y0, b.y1)
+			wg.Done()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,138 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Params holds an effect's settings from a cue's "params" object in the cue
+// file, as decoded from JSON (numbers are float64, lists are []any).
+type Params map[string]any
+
+// Configurable is implemented by effects that take settings from the cue
+// file. The sequencer calls Configure when a cue for the effect becomes
+// active, before Init, so palettes chosen in Configure are applied in Init.
+// A cue without params configures the effect with nil, i.e. its defaults.
+type Configurable interface {
+	Configure(p Params) error
+}
+
+// String returns the string at key, or def if it is missing.
+func (p Params) String(key, def string) string {
+	if s, ok := p[key].(string); ok {
+		return s
+	}
+	return def
+}
+
+// Float returns the number at key, or def if it is missing.
+func (p Params) Float(key string, def float64) float64 {
+	if f, ok := p[key].(float64); ok {
+		return f
+	}
+	return def
+}
+
+// Int returns the number at key truncated to an int, or def if it is missing.
+func (p Params) Int(key string, def int) int {
+	if f, ok := p[key].(float64); ok {
+		return int(f)
+	}
+	return def
+}
+
+// Bool returns the boolean at key, or def if it is missing.
+func (p Params) Bool(key string, def bool) bool {
+	if b, ok := p[key].(bool); ok {
+		return b
+	}
+	return def
+}
+
+// Palette reads a palette setting: either a built-in name (see
+// vga.PaletteByName) or a list of "#rrggbb" gradient stops. ok is false if
+// the key is missing.
+func (p Params) Palette(key string) (pal vga.Palette, ok bool, err error) {
+	switch v := p[key].(type) {
+	case nil:
+		return pal, false, nil
+	case string:
+		pal, ok := vga.PaletteByName(v)
+		if !ok {
+			return pal, false, fmt.Errorf("%s: unknown palette %q", key, v)
+		}
+		return pal, true, nil
+	case []any:
+		stops := make([]color.RGBA, len(v))
+		for i, s := range v {
+			str, _ := s.(string)
+			c, err := parseColor(str)
+			if err != nil {
+				return pal, false, fmt.Errorf("%s: %w", key, err)
+			}
+			stops[i] = c
+		}
+		return vga.RampPalette(stops...), true, nil
+	}
+	return pal, false, fmt.Errorf("%s: palette must be a name or a list of colours", key)
+}
+
+// parseColor parses "#rrggbb".
+func parseColor(s string) (color.RGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil || len(h) != 6 {
+		return color.RGBA{}, fmt.Errorf("bad colour %q (want #rrggbb)", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// texture is a loaded or generated texture and the palette it came with.
+type texture struct {
+	sprite *vga.Sprite
+	pal    vga.Palette
+}
+
+// textureCache keeps loaded textures so re-configuring an effect on every
+// cue does not hit the disk again.
+var textureCache struct {
+	sync.Mutex
+	m map[string]texture
+}
+
+// Texture reads a texture setting: "xor" or "checker" for the built-in
+// 256x256 patterns (the checker uses the two ends of its palette, so it
+// follows any gradient given as "palette"), anything else is a PNG path (see vga.LoadSpritePNG).
+// def is used when the key is missing.
+func (p Params) Texture(key, def string) (*vga.Sprite, vga.Palette, error) {
+	spec := p.String(key, def)
+	textureCache.Lock()
+	defer textureCache.Unlock()
+	if t, ok := textureCache.m[spec]; ok {
+		return t.sprite, t.pal, nil
+	}
+
+	var t texture
+	switch spec {
+	case "xor":
+		t = texture{vga.XORTexture(256, 256), vga.PlasmaPalette()}
+	case "checker":
+		t = texture{vga.CheckerTexture(256, 256, 32, 0, 255), vga.GradientPalette(color.RGBA{0, 0, 170, 255}, color.RGBA{255, 255, 255, 255})}
+	default:
+		s, pal, err := vga.LoadSpritePNG(spec)
+		if err != nil {
+			return nil, pal, fmt.Errorf("%s: %w", key, err)
+		}
+		t = texture{s, pal}
+	}
+	if textureCache.m == nil {
+		textureCache.m = make(map[string]texture)
+	}
+	textureCache.m[spec] = t
+	return t.sprite, t.pal, nil
+}
@@ -0,0 +1,153 @@
+package effects
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// ColorRamp is the run of palette entries a particle fades through over its
+// life: Start at birth, End at death.
+type ColorRamp struct {
+	Start, End byte
+}
+
+// At returns the entry for a particle t (0-1) of the way through its life.
+func (r ColorRamp) At(t float64) byte {
+	return byte(float64(r.Start) + (float64(r.End)-float64(r.Start))*math.Min(math.Max(t, 0), 1) + 0.5)
+}
+
+// Particle is a single point with a position and velocity in the
+// system's coordinate space and an age in seconds.
+type Particle struct {
+	X, Y   float64
+	VX, VY float64
+	Age    float64
+	Life   float64
+	Ramp   ColorRamp
+}
+
+// ParticleSystem is a fixed-size pool of particles under gravity and drag.
+// Nothing is allocated after NewParticleSystem: spawning past capacity is
+// dropped, and dead particles are swapped out of the live range. All
+// randomness comes from Rng, so a given seed and sequence of calls always
+// produces the same particles.
+type ParticleSystem struct {
+	Gravity float64 // downward acceleration, units per second squared
+	Drag    float64 // fraction of velocity lost per second
+	Rng     *rand.Rand
+
+	pool []Particle
+	live int
+}
+
+func NewParticleSystem(capacity int, seed int64) *ParticleSystem {
+	return &ParticleSystem{
+		Rng:  rand.New(rand.NewSource(seed)),
+		pool: make([]Particle, capacity),
+	}
+}
+
+// Reset kills every particle and restarts the random sequence from seed.
+func (ps *ParticleSystem) Reset(seed int64) {
+	ps.live = 0
+	ps.Rng.Seed(seed)
+}
+
+// Live returns the live particles; the slice is only valid until the next
+// Spawn or Update.
+func (ps *ParticleSystem) Live() []Particle {
+	return ps.pool[:ps.live]
+}
+
+// Spawn adds a particle, reporting false if the pool is full.
+func (ps *ParticleSystem) Spawn(p Particle) bool {
+	if ps.live == len(ps.pool) {
+		return false
+	}
+	ps.pool[ps.live] = p
+	ps.live++
+	return true
+}
+
+// Burst spawns n particles at (x, y) flying out in all directions with
+// speeds up to speed, living life seconds give or take a quarter.
+func (ps *ParticleSystem) Burst(x, y float64, n int, speed, life float64, ramp ColorRamp) {
+	for i := 0; i < n; i++ {
+		// Uniform over the disc of velocities, so bursts look spherical.
+		a := ps.Rng.Float64() * 2 * math.Pi
+		v := speed * math.Sqrt(ps.Rng.Float64())
+		s, c := math.Sincos(a)
+		ps.Spawn(Particle{
+			X: x, Y: y, VX: c * v, VY: s * v,
+			Life: life * (0.75 + 0.5*ps.Rng.Float64()),
+			Ramp: ramp,
+		})
+	}
+}
+
+// Update ages and moves every particle by dt seconds and removes the dead.
+func (ps *ParticleSystem) Update(dt float64) {
+	drag := math.Pow(1-math.Min(ps.Drag, 1), dt)
+	for i := 0; i < ps.live; {
+		p := &ps.pool[i]
+		p.Age += dt
+		if p.Age >= p.Life {
+			ps.live--
+			ps.pool[i] = ps.pool[ps.live]
+			continue
+		}
+		p.VY += ps.Gravity * dt
+		p.VX *= drag
+		p.VY *= drag
+		p.X += p.VX * dt
+		p.Y += p.VY * dt
+		i++
+	}
+}
+
+// Draw plots every particle as a size x size square, with the system's
+// coordinates scaled by sx and sy into fb. With a blend table (e.g.
+// vga.AdditiveTable) particles are blended over what is already there.
+func (ps *ParticleSystem) Draw(fb *vga.Framebuffer, sx, sy float64, size int, blend *vga.BlendTable) {
+	for _, p := range ps.pool[:ps.live] {
+		x, y := int(p.X*sx), int(p.Y*sy)
+		c := p.Ramp.At(p.Age / p.Life)
+		if blend != nil {
+			fb.FillRectBlend(x, y, size, size, c, blend)
+		} else {
+			fb.FillRect(x, y, size, size, c)
+		}
+	}
+}
+
+// Emitter spawns particles continuously from a point: Rate per second,
+// heading Angle (radians, 0 is +X, system Y points down) give or take
+// Spread, at Speed give or take a quarter.
+type Emitter struct {
+	X, Y   float64
+	Rate   float64
+	Angle  float64
+	Spread float64
+	Speed  float64
+	Life   float64
+	Ramp   ColorRamp
+
+	pending float64
+}
+
+// Emit spawns the particles due over dt seconds into ps.
+func (e *Emitter) Emit(ps *ParticleSystem, dt float64) {
+	e.pending += e.Rate * dt
+	for ; e.pending >= 1; e.pending-- {
+		a := e.Angle + (ps.Rng.Float64()*2-1)*e.Spread
+		v := e.Speed * (0.75 + 0.5*ps.Rng.Float64())
+		s, c := math.Sincos(a)
+		ps.Spawn(Particle{
+			X: e.X, Y: e.Y, VX: c * v, VY: s * v,
+			Life: e.Life * (0.75 + 0.5*ps.Rng.Float64()),
+			Ramp: e.Ramp,
+		})
+	}
+}
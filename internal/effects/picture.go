@@ -0,0 +1,89 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Picture shows an image, typically a logo layered over another effect
+// (see Layers). Index 0 is transparent; the image's other colours are moved
+// up by palette_base so they can share the palette with the effect
+// underneath.
+//
+// Cue params: "image" (see Params.Texture), "palette_base", "x" and "y"
+// (centre as a fraction of the screen, default 0.5) and "bounce" (pixels
+// the image hops on each beat).
+type Picture struct {
+	sprite *vga.Sprite
+	pal    vga.Palette
+	used   int // highest colour index the image uses
+	base   int
+	remap  vga.RemapTable
+	x, y   motion
+	bounce float64
+	beat   float64
+}
+
+func NewPicture() *Picture {
+	p := &Picture{}
+	p.Configure(nil)
+	return p
+}
+
+// Configure applies cue params; see Configurable.
+func (p *Picture) Configure(params Params) error {
+	s, pal, err := params.Texture("image", "xor")
+	if err != nil {
+		return err
+	}
+	used := 0
+	for _, px := range s.Pixels {
+		used = max(used, int(px))
+	}
+	base := params.Int("palette_base", 0)
+	if base < 0 || base+used > 255 {
+		return fmt.Errorf("palette_base: image colours 1-%d moved by %d leave the palette", used, base)
+	}
+	x, err := params.animated("x", 0.5)
+	if err != nil {
+		return err
+	}
+	y, err := params.animated("y", 0.5)
+	if err != nil {
+		return err
+	}
+	p.sprite, p.pal, p.used, p.base = s, pal, used, base
+	p.remap = vga.OffsetRemap(base)
+	p.x, p.y = x, y
+	p.bounce = params.Float("bounce", 0)
+	return nil
+}
+
+func (p *Picture) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(blackPalette())
+	p.InitOverlay(fb)
+}
+
+// InitOverlay sets only the image's colours; see Overlay.
+func (p *Picture) InitOverlay(fb *vga.Framebuffer) {
+	for i := 1; i <= p.used; i++ {
+		fb.SetPaletteColor(byte(p.base+i), p.pal[i])
+	}
+}
+
+func (p *Picture) Update(dt float64, sync music.FrameInfo) {
+	p.x.update(dt, 1, sync)
+	p.y.update(dt, 1, sync)
+	p.beat = sync.BeatPulse()
+}
+
+func (p *Picture) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	hop := p.bounce * math.Sin(p.beat*math.Pi/2)
+	x := int(p.x.value*float64(fb.Width)) - p.sprite.Width/2
+	y := int(p.y.value*float64(fb.Height)-hop) - p.sprite.Height/2
+	fb.DrawSpriteEx(x, y, p.sprite, vga.SpriteOptions{Remap: &p.remap})
+}
@@ -38,12 +38,17 @@ func (p *Plasma) Update(dt float64, sync music.FrameInfo) {
 }
 
 func (p *Plasma) Draw(fb *vga.Framebuffer) {
+	DrawParallel(fb, p)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (p *Plasma) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
 	t := p.time * 50.0
 
-	for y := 0; y < vga.Height; y++ {
+	for y := y0; y < y1; y++ {
 		fy := float64(y)
-		off := y * vga.Width
-		for x := 0; x < vga.Width; x++ {
+		off := y * fb.Stride
+		for x := 0; x < fb.Width; x++ {
 			fx := float64(x)
 
 			v := p.sin(fx*0.08 + t*0.03)
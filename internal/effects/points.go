@@ -0,0 +1,61 @@
+package effects
+
+import (
+	"slices"
+
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// ProjectedPoint is a PointCloud point after projection: screen position,
+// camera-space depth and the index of the source point.
+type ProjectedPoint struct {
+	X, Y  int
+	Z     float64
+	Index int
+}
+
+// PointCloud is the transform pipeline shared by point-based 3D effects
+// (dots, vector balls, stars): points are rotated and scaled by a matrix,
+// moved by an offset into camera space, perspective-projected and culled
+// against the near plane and the screen. Effects fill Points however they
+// like, every frame if the points move.
+type PointCloud struct {
+	Points []Vec3
+	Near   float64 // points at or nearer than this depth (at least 0) are dropped
+
+	proj []ProjectedPoint
+}
+
+// Project transforms and projects the points onto fb with the given focal
+// length in pixels, keeping points up to margin pixels outside the screen
+// (for dots drawn as sprites). The result is reused by the next call.
+func (pc *PointCloud) Project(fb *vga.Framebuffer, xf Mat3, offset Vec3, focal float64, margin int) []ProjectedPoint {
+	cx, cy := float64(fb.Width)/2, float64(fb.Height)/2
+	pc.proj = pc.proj[:0]
+	for i, p := range pc.Points {
+		c := xf.Apply(p).Add(offset)
+		if c.Z <= max(pc.Near, 0) {
+			continue
+		}
+		x := int(cx + c.X/c.Z*focal)
+		y := int(cy - c.Y/c.Z*focal)
+		if x < -margin || x >= fb.Width+margin || y < -margin || y >= fb.Height+margin {
+			continue
+		}
+		pc.proj = append(pc.proj, ProjectedPoint{x, y, c.Z, i})
+	}
+	return pc.proj
+}
+
+// SortFarToNear orders projected points for painter's-order drawing.
+func SortFarToNear(pts []ProjectedPoint) {
+	slices.SortFunc(pts, func(a, b ProjectedPoint) int {
+		switch {
+		case a.Z > b.Z:
+			return -1
+		case a.Z < b.Z:
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,124 @@
+package effects
+
+import (
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Rotozoom is the classic rotating and zooming tiled texture. Rotation
+// speeds up on each beat and the zoom punches in with the music's volume.
+//
+// Cue params: "texture" ("xor", "checker" or a PNG path), "palette"
+// (overrides the texture's palette), "spin" (radians per second) and
+// "zoom" (base magnification).
+type Rotozoom struct {
+	tex  *vga.Sprite
+	pal  vga.Palette
+	spin float64
+	base float64
+
+	time  float64
+	angle float64
+	zoom  float64
+	pulse float64
+	vol   float64
+
+	// Per-frame texture stepping in 16.16 fixed point, set by Draw.
+	u0, v0     int
+	dudx, dvdx int
+	dudy, dvdy int
+}
+
+func NewRotozoom() *Rotozoom {
+	r := &Rotozoom{}
+	r.Configure(nil)
+	return r
+}
+
+// Configure applies cue params; see Configurable.
+func (r *Rotozoom) Configure(p Params) error {
+	tex, pal, err := p.Texture("texture", "xor")
+	if err != nil {
+		return err
+	}
+	if custom, ok, err := p.Palette("palette"); err != nil {
+		return err
+	} else if ok {
+		pal = custom
+	}
+	r.tex, r.pal = tex, pal
+	r.spin = p.Float("spin", 0.6)
+	r.base = p.Float("zoom", 1.0)
+	return nil
+}
+
+func (r *Rotozoom) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(r.pal)
+}
+
+func (r *Rotozoom) Update(dt float64, sync music.FrameInfo) {
+	speed := 1.0
+	if sync.BPM > 0 {
+		speed = float64(sync.BPM) / 120.0
+	}
+	r.pulse = sync.BeatPulse()
+	r.vol = sync.MaxChannelVolume()
+
+	r.time += dt * speed
+	r.angle += dt * speed * r.spin * (1 + 2*r.pulse)
+	r.zoom = r.base * (1.2 + 0.6*math.Sin(r.time*0.5)) * (1 - 0.3*r.pulse*r.vol)
+	r.zoom = math.Max(r.zoom, 0.05)
+}
+
+func (r *Rotozoom) Draw(fb *vga.Framebuffer) {
+	// Texels per screen pixel, scaled so larger modes show the same view.
+	step := 320.0 / float64(fb.Width) / r.zoom
+	const one = 1 << 16
+	cos := math.Cos(r.angle) * step
+	sin := math.Sin(r.angle) * step
+	r.dudx, r.dvdx = int(cos*one), int(sin*one)
+	r.dudy, r.dvdy = int(-sin*one), int(cos*one)
+
+	// The centre of rotation drifts across the texture.
+	cu := float64(r.tex.Width) * (0.5 + math.Sin(r.time*0.3))
+	cv := float64(r.tex.Height) * (0.5 + math.Cos(r.time*0.23))
+	hw, hh := float64(fb.Width)/2, float64(fb.Height)/2
+	r.u0 = int((cu - hw*cos + hh*sin) * one)
+	r.v0 = int((cv - hw*sin - hh*cos) * one)
+
+	DrawParallel(fb, r)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer.
+func (r *Rotozoom) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
+	tw, th := r.tex.Width, r.tex.Height
+	pow2 := tw&(tw-1) == 0 && th&(th-1) == 0
+	for y := y0; y < y1; y++ {
+		u := r.u0 + y*r.dudy
+		v := r.v0 + y*r.dvdy
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		for x := range row {
+			tx, ty := u>>16, v>>16
+			if pow2 {
+				tx &= tw - 1
+				ty &= th - 1
+			} else {
+				tx, ty = wrapTexel(tx, tw), wrapTexel(ty, th)
+			}
+			row[x] = r.tex.Pixels[ty*tw+tx]
+			u += r.dudx
+			v += r.dvdx
+		}
+	}
+}
+
+// wrapTexel tiles coordinate i into [0, n).
+func wrapTexel(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
@@ -13,12 +13,12 @@ type SineScroller struct {
 	time      float64
 	charWidth int
 	speed     float64
+	width     int // framebuffer width the scroll wraps at
 }
 
 func NewSineScroller(text string) *SineScroller {
 	return &SineScroller{
 		text:      text + "  ",
-		offset:    float64(vga.Width),
 		charWidth: 8,
 		speed:     60,
 	}
@@ -27,6 +27,10 @@ func NewSineScroller(text string) *SineScroller {
 func (s *SineScroller) Init(fb *vga.Framebuffer) {
 	fb.SetPalette(vga.DefaultPalette())
 	vga.LoadFontFromPNG("assets/font1.png")
+	if s.width != fb.Width {
+		s.width = fb.Width
+		s.offset = float64(fb.Width)
+	}
 }
 
 func (s *SineScroller) Update(dt float64, sync music.FrameInfo) {
@@ -37,12 +41,12 @@ func (s *SineScroller) Update(dt float64, sync music.FrameInfo) {
 	}
 	s.offset -= dt * speed
 	if s.offset < -float64(len(s.text)*s.charWidth) {
-		s.offset = float64(vga.Width)
+		s.offset = float64(s.width)
 	}
 }
 
 func (s *SineScroller) Draw(fb *vga.Framebuffer) {
-	centerY := vga.Height / 2
+	centerY := fb.Height / 2
 	amp := 20.0 + math.Sin(s.time*2)*10
 	freq := 0.1 + math.Sin(s.time)*0.05
 
@@ -55,7 +59,7 @@ func (s *SineScroller) Draw(fb *vga.Framebuffer) {
 					sineY := math.Sin(float64(charX+fx)*freq+s.time*3) * amp
 					py := centerY - 4 + fy + int(sineY)
 					px := charX + fx
-					if px >= 0 && px < vga.Width && py >= 0 && py < vga.Height {
+					if px >= 0 && px < fb.Width && py >= 0 && py < fb.Height {
 						fb.SetPixel(px, py, 255)
 					}
 				}
@@ -70,20 +74,24 @@ type BigScroller struct {
 	time   float64
 	speed  float64
 	scale  int
+	width  int // framebuffer width the scroll wraps at
 }
 
 func NewBigScroller(text string) *BigScroller {
 	return &BigScroller{
-		text:   text + "  ",
-		offset: float64(vga.Width),
-		speed:  60,
-		scale:  3,
+		text:  text + "  ",
+		speed: 60,
+		scale: 3,
 	}
 }
 
 func (b *BigScroller) Init(fb *vga.Framebuffer) {
 	fb.SetPalette(vga.DefaultPalette())
 	vga.LoadFontFromPNG("assets/font1.png")
+	if b.width != fb.Width {
+		b.width = fb.Width
+		b.offset = float64(fb.Width)
+	}
 }
 
 func (b *BigScroller) Update(dt float64, sync music.FrameInfo) {
@@ -94,12 +102,12 @@ func (b *BigScroller) Update(dt float64, sync music.FrameInfo) {
 	}
 	b.offset -= dt * speed
 	if b.offset < -float64(len(b.text)*8*b.scale) {
-		b.offset = float64(vga.Width)
+		b.offset = float64(b.width)
 	}
 }
 
 func (b *BigScroller) Draw(fb *vga.Framebuffer) {
-	centerY := (vga.Height - 8*b.scale) / 2
+	centerY := (fb.Height - 8*b.scale) / 2
 
 	for i, ch := range b.text {
 		charX := int(b.offset) + i*8*b.scale
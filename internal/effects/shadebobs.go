@@ -0,0 +1,210 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+const (
+	maxBobs = 32
+	// bobStep is the fixed stamping step: bobs are added along their paths
+	// at this rate whatever the frame rate, so trails build up the same way
+	// in live playback and offline renders.
+	bobStep = 1.0 / 60
+)
+
+// Shadebobs are blobs moving on Lissajous paths that add to the palette
+// index of every pixel under them, so the screen builds up trails that
+// climb a gradient palette where paths cross. Indices either saturate at
+// 255 or wrap around (through a mirrored palette, so wrapping is seamless).
+// Every few tracker rows the whole image fades down again.
+//
+// The image accumulates in the effect's own buffer rather than in the
+// framebuffer, so crossfades and layering don't feed back into it.
+//
+// Cue params: "bobs" (count), "extra" (bobs added at full channel volume),
+// "radius" (fraction of screen height), "strength" (added per step),
+// "mode" ("saturate" or "wrap"), "fade_rows" (rows between fades, 0 for
+// none), "fade" (how far each fade lowers the image), "speed" and "palette".
+type Shadebobs struct {
+	count    int
+	extra    int
+	radius   float64
+	strength int
+	wrap     bool
+	fadeRows int
+	fadeBy   int
+	speed    float64
+	pal      vga.Palette
+
+	time     float64
+	pending  float64   // unstamped time, in seconds
+	steps    []float64 // path times still to stamp
+	active   float64   // bob count, following the music smoothly
+	lastFade int
+	fadeLeft int
+
+	// Rebuilt when the framebuffer size changes.
+	w, h  int
+	acc   []byte
+	stamp []bool
+	r     int
+}
+
+func NewShadebobs() *Shadebobs {
+	s := &Shadebobs{}
+	s.Configure(nil)
+	return s
+}
+
+// Configure applies cue params; see Configurable.
+func (s *Shadebobs) Configure(p Params) error {
+	count, extra := p.Int("bobs", 8), p.Int("extra", 8)
+	if count < 1 || extra < 0 || count+extra > maxBobs {
+		return fmt.Errorf("bobs: bobs plus extra must be 1-%d", maxBobs)
+	}
+	var wrap bool
+	switch mode := p.String("mode", "saturate"); mode {
+	case "saturate", "wrap":
+		wrap = mode == "wrap"
+	default:
+		return fmt.Errorf("mode: unknown mode %q", mode)
+	}
+	src, ok, err := p.Palette("palette")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		src = vga.RampPalette(
+			color.RGBA{0, 0, 0, 255}, color.RGBA{16, 24, 112, 255}, color.RGBA{144, 32, 160, 255},
+			color.RGBA{255, 144, 48, 255}, color.RGBA{255, 255, 224, 255})
+	}
+
+	s.count, s.extra, s.wrap = count, extra, wrap
+	s.radius = p.Float("radius", 0.08)
+	s.strength = max(p.Int("strength", 2), 1)
+	s.fadeRows = p.Int("fade_rows", 32)
+	s.fadeBy = p.Int("fade", 160)
+	s.speed = p.Float("speed", 1)
+	s.pal = src
+	if s.wrap {
+		// Up the gradient and back down, so 255 wraps to 0 without a seam.
+		for i := 0; i < 128; i++ {
+			s.pal[i] = src[i*2]
+			s.pal[255-i] = src[i*2]
+		}
+	}
+	s.w = 0 // the stamp depends on the radius
+	return nil
+}
+
+func (s *Shadebobs) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(s.pal)
+	s.w = 0 // start from a black screen
+	s.steps = s.steps[:0]
+	s.pending = 0
+	s.active = float64(s.count)
+	s.lastFade = -1
+	s.fadeLeft = 0
+}
+
+func (s *Shadebobs) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	rate := s.speed * tempo * (1 + 0.5*sync.BeatPulse())
+
+	target := float64(s.count) + float64(s.extra)*sync.MaxChannelVolume()
+	s.active += (target - s.active) * math.Min(1, dt*4)
+
+	s.pending += dt
+	for ; s.pending >= bobStep; s.pending -= bobStep {
+		s.time += bobStep * rate
+		// After a stall, only stamp the most recent part of the paths.
+		if len(s.steps) == 8 {
+			copy(s.steps, s.steps[1:])
+			s.steps = s.steps[:7]
+		}
+		s.steps = append(s.steps, s.time)
+	}
+
+	if s.fadeRows > 0 {
+		row := int(float64(sync.Order)*rowsPerOrder(sync)) + sync.Row
+		if sync.BPM == 0 {
+			row = int(s.time * 8) // no music: about 8 rows a second
+		}
+		if n := row / s.fadeRows; n != s.lastFade {
+			if s.lastFade >= 0 {
+				s.fadeLeft = s.fadeBy
+			}
+			s.lastFade = n
+		}
+	}
+}
+
+// resize reallocates the accumulation buffer and the bob's disc mask.
+func (s *Shadebobs) resize(fb *vga.Framebuffer) {
+	s.w, s.h = fb.Width, fb.Height
+	s.acc = make([]byte, s.w*s.h)
+	s.r = max(int(s.radius*float64(s.h)), 1)
+	d := 2*s.r + 1
+	s.stamp = make([]bool, d*d)
+	for y := -s.r; y <= s.r; y++ {
+		for x := -s.r; x <= s.r; x++ {
+			s.stamp[(y+s.r)*d+x+s.r] = x*x+y*y <= s.r*s.r
+		}
+	}
+}
+
+func (s *Shadebobs) Draw(fb *vga.Framebuffer) {
+	if s.w != fb.Width || s.h != fb.Height {
+		s.resize(fb)
+	}
+	n := min(int(s.active+0.5), s.count+s.extra)
+	for _, t := range s.steps {
+		for i := 0; i < n; i++ {
+			fi := float64(i)
+			x := 0.5 + 0.42*math.Sin(t*(0.61+0.07*fi)+fi*0.9)
+			y := 0.5 + 0.40*math.Sin(t*(0.83+0.05*fi)+fi*1.4+math.Pi/2)
+			s.add(int(x*float64(s.w)), int(y*float64(s.h)))
+		}
+		if s.fadeLeft > 0 {
+			// Fade gradually, 2 levels per step.
+			by := min(s.fadeLeft, 2)
+			for i, v := range s.acc {
+				s.acc[i] = byte(max(int(v)-by, 0))
+			}
+			s.fadeLeft -= by
+		}
+	}
+	s.steps = s.steps[:0]
+
+	for y := 0; y < s.h; y++ {
+		copy(fb.Pixels[y*fb.Stride:y*fb.Stride+s.w], s.acc[y*s.w:(y+1)*s.w])
+	}
+}
+
+// add stamps one bob centred on (cx, cy), clipped to the screen.
+func (s *Shadebobs) add(cx, cy int) {
+	d := 2*s.r + 1
+	inc := s.strength
+	for y := max(cy-s.r, 0); y <= min(cy+s.r, s.h-1); y++ {
+		mask := s.stamp[(y-cy+s.r)*d:]
+		row := s.acc[y*s.w:]
+		for x := max(cx-s.r, 0); x <= min(cx+s.r, s.w-1); x++ {
+			if !mask[x-cx+s.r] {
+				continue
+			}
+			if s.wrap {
+				row[x] += byte(inc)
+			} else {
+				row[x] = byte(min(int(row[x])+inc, 255))
+			}
+		}
+	}
+}
@@ -9,52 +9,63 @@ import (
 
 const numStars = 512
 
-type star struct {
-	x, y, z float64
-}
-
 // Starfield is a classic 3D parallax starfield flying through space.
+//
+// Cue params: "spin" (roll rate in radians per second at 120 BPM).
 type Starfield struct {
-	stars [numStars]star
+	cloud PointCloud
 	speed float64
+	spin  float64
+	roll  float64
 }
 
 func NewStarfield() *Starfield {
 	sf := &Starfield{speed: 1.0}
-	for i := range sf.stars {
-		sf.stars[i] = sf.randomStar()
-		sf.stars[i].z = rand.Float64() * 256.0 // spread initial depth
+	sf.cloud.Points = make([]Vec3, numStars)
+	sf.cloud.Near = 1
+	for i := range sf.cloud.Points {
+		sf.cloud.Points[i] = sf.randomStar()
+		sf.cloud.Points[i].Z = rand.Float64() * 256.0 // spread initial depth
 	}
 	return sf
 }
 
-func (sf *Starfield) randomStar() star {
-	return star{
-		x: (rand.Float64() - 0.5) * 640.0,
-		y: (rand.Float64() - 0.5) * 400.0,
-		z: 256.0,
+func (sf *Starfield) randomStar() Vec3 {
+	return Vec3{
+		X: (rand.Float64() - 0.5) * 640.0,
+		Y: (rand.Float64() - 0.5) * 400.0,
+		Z: 256.0,
 	}
 }
 
+// Configure applies cue params; see Configurable.
+func (sf *Starfield) Configure(p Params) error {
+	sf.spin = p.Float("spin", 0)
+	return nil
+}
+
 func (sf *Starfield) Init(fb *vga.Framebuffer) {
 	fb.SetPalette(vga.DefaultPalette())
 }
 
 func (sf *Starfield) Update(dt float64, sync music.FrameInfo) {
 	sf.speed = 200.0
+	tempo := 1.0
 	if sync.BPM > 0 {
 		sf.speed = float64(sync.BPM) * 1.5
+		tempo = float64(sync.BPM) / 120.0
 		// Warp speed on beat
 		if sync.Speed > 0 && sync.Frame == 0 {
 			sf.speed *= 3.0
 		}
 	}
+	sf.roll += sf.spin * tempo * dt
 
 	// Move stars toward the viewer
-	for i := range sf.stars {
-		sf.stars[i].z -= sf.speed * dt
-		if sf.stars[i].z <= 1.0 {
-			sf.stars[i] = sf.randomStar()
+	for i := range sf.cloud.Points {
+		sf.cloud.Points[i].Z -= sf.speed * dt
+		if sf.cloud.Points[i].Z <= 1.0 {
+			sf.cloud.Points[i] = sf.randomStar()
 		}
 	}
 }
@@ -62,21 +73,12 @@ func (sf *Starfield) Update(dt float64, sync music.FrameInfo) {
 func (sf *Starfield) Draw(fb *vga.Framebuffer) {
 	fb.Clear(0) // black background
 
-	cx := float64(vga.Width) / 2.0
-	cy := float64(vga.Height) / 2.0
-
-	for i := range sf.stars {
-		s := &sf.stars[i]
-		// Project 3D → 2D
-		sx := int(s.x/s.z*128.0 + cx)
-		sy := int(s.y/s.z*128.0 + cy)
-
-		if sx < 0 || sx >= vga.Width || sy < 0 || sy >= vga.Height {
-			continue
-		}
+	// Field of view scales with width so larger modes see the same starfield.
+	fov := 128.0 * float64(fb.Width) / 320.0
 
+	for _, s := range sf.cloud.Project(fb, RotationXYZ(0, 0, sf.roll), Vec3{}, fov, 0) {
 		// Brightness based on depth (closer = brighter)
-		bright := int(255.0 - s.z)
+		bright := int(255.0 - s.Z)
 		if bright < 0 {
 			bright = 0
 		}
@@ -91,6 +93,6 @@ func (sf *Starfield) Draw(fb *vga.Framebuffer) {
 			colorIdx = 8 // dark gray
 		}
 
-		fb.Pixels[sy*vga.Width+sx] = colorIdx
+		fb.Pixels[s.Y*fb.Stride+s.X] = colorIdx
 	}
 }
@@ -9,36 +9,49 @@ import (
 
 // Tunnel is a classic texture-mapped tunnel/wormhole effect.
 type Tunnel struct {
-	// Pre-computed lookup tables
-	angleLUT [vga.Size]float64
-	depthLUT [vga.Size]float64
+	// Pre-computed lookup tables, rebuilt when the framebuffer size changes
+	angleLUT []float64
+	depthLUT []float64
+	w, h     int
 	time     float64
 }
 
 func NewTunnel() *Tunnel {
-	t := &Tunnel{}
-	cx := float64(vga.Width) / 2.0
-	cy := float64(vga.Height) / 2.0
+	return &Tunnel{}
+}
+
+// buildLUTs computes the per-pixel depth and angle tables for a w x h screen.
+func (t *Tunnel) buildLUTs(w, h int) {
+	if t.w == w && t.h == h {
+		return
+	}
+	t.w, t.h = w, h
+	t.angleLUT = make([]float64, w*h)
+	t.depthLUT = make([]float64, w*h)
+	cx := float64(w) / 2.0
+	cy := float64(h) / 2.0
+	// Keep the tunnel the same apparent size as at 320 pixels wide.
+	depthScale := 128.0 * float64(w) / 320.0
 
-	for y := 0; y < vga.Height; y++ {
-		for x := 0; x < vga.Width; x++ {
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
 			dx := float64(x) - cx
 			dy := float64(y) - cy
 			dist := math.Sqrt(dx*dx + dy*dy)
-			idx := y*vga.Width + x
+			idx := y*w + x
 
 			if dist < 1.0 {
 				dist = 1.0
 			}
-			t.depthLUT[idx] = 128.0 / dist
+			t.depthLUT[idx] = depthScale / dist
 			t.angleLUT[idx] = math.Atan2(dy, dx) / math.Pi * 128.0
 		}
 	}
-	return t
 }
 
 func (t *Tunnel) Init(fb *vga.Framebuffer) {
 	fb.SetPalette(vga.PlasmaPalette())
+	t.buildLUTs(fb.Width, fb.Height)
 }
 
 func (t *Tunnel) Update(dt float64, sync music.FrameInfo) {
@@ -50,14 +63,25 @@ func (t *Tunnel) Update(dt float64, sync music.FrameInfo) {
 }
 
 func (t *Tunnel) Draw(fb *vga.Framebuffer) {
+	t.buildLUTs(fb.Width, fb.Height)
+	DrawParallel(fb, t)
+}
+
+// DrawRows renders rows [y0, y1); see RowDrawer. The LUTs must already match
+// the framebuffer size (Draw rebuilds them before splitting into bands).
+func (t *Tunnel) DrawRows(fb *vga.Framebuffer, y0, y1 int) {
 	shiftU := t.time * 50.0
 	shiftV := t.time * 30.0
 
-	for i := 0; i < vga.Size; i++ {
-		u := int(t.depthLUT[i]+shiftU) & 255
-		v := int(t.angleLUT[i]+shiftV) & 255
+	for y := y0; y < min(y1, t.h); y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+t.w]
+		for x := range row {
+			i := y*t.w + x
+			u := int(t.depthLUT[i]+shiftU) & 255
+			v := int(t.angleLUT[i]+shiftV) & 255
 
-		// XOR texture — classic demoscene pattern
-		fb.Pixels[i] = byte(u ^ v)
+			// XOR texture — classic demoscene pattern
+			row[x] = byte(u ^ v)
+		}
 	}
 }
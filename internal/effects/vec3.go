@@ -0,0 +1,78 @@
+package effects
+
+import "math"
+
+// Vec3 is a point or direction in 3D space. The camera looks down +Z with
+// +Y up.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (a Vec3) Add(b Vec3) Vec3             { return Vec3{a.X + b.X, a.Y + b.Y, a.Z + b.Z} }
+func (a Vec3) Sub(b Vec3) Vec3             { return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z} }
+func (a Vec3) Scale(s float64) Vec3        { return Vec3{a.X * s, a.Y * s, a.Z * s} }
+func (a Vec3) Dot(b Vec3) float64          { return a.X*b.X + a.Y*b.Y + a.Z*b.Z }
+func (a Vec3) Len() float64                { return math.Sqrt(a.Dot(a)) }
+func (a Vec3) Lerp(b Vec3, t float64) Vec3 { return a.Add(b.Sub(a).Scale(t)) }
+
+func (a Vec3) Cross(b Vec3) Vec3 {
+	return Vec3{a.Y*b.Z - a.Z*b.Y, a.Z*b.X - a.X*b.Z, a.X*b.Y - a.Y*b.X}
+}
+
+// Normalize returns a unit vector in the same direction (or a zero vector
+// unchanged).
+func (a Vec3) Normalize() Vec3 {
+	l := a.Len()
+	if l == 0 {
+		return a
+	}
+	return a.Scale(1 / l)
+}
+
+// Mat3 is a 3x3 matrix, row-major, used for rotation and scale.
+type Mat3 [9]float64
+
+// Identity3 returns the identity matrix.
+func Identity3() Mat3 {
+	return Mat3{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// RotationXYZ returns the rotation by ax around X, then ay around Y, then az
+// around Z (radians).
+func RotationXYZ(ax, ay, az float64) Mat3 {
+	sx, cx := math.Sincos(ax)
+	sy, cy := math.Sincos(ay)
+	sz, cz := math.Sincos(az)
+	rx := Mat3{1, 0, 0, 0, cx, -sx, 0, sx, cx}
+	ry := Mat3{cy, 0, sy, 0, 1, 0, -sy, 0, cy}
+	rz := Mat3{cz, -sz, 0, sz, cz, 0, 0, 0, 1}
+	return rz.Mul(ry.Mul(rx))
+}
+
+// Mul returns m*n (n applied first).
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var r Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i*3+j] = m[i*3]*n[j] + m[i*3+1]*n[3+j] + m[i*3+2]*n[6+j]
+		}
+	}
+	return r
+}
+
+// Scaled returns m with a uniform scale s applied.
+func (m Mat3) Scaled(s float64) Mat3 {
+	for i := range m {
+		m[i] *= s
+	}
+	return m
+}
+
+// Apply transforms v by m.
+func (m Mat3) Apply(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v.X + m[1]*v.Y + m[2]*v.Z,
+		m[3]*v.X + m[4]*v.Y + m[5]*v.Z,
+		m[6]*v.X + m[7]*v.Y + m[8]*v.Z,
+	}
+}
@@ -0,0 +1,248 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Palette layout for shaded polygons: numRamps ramps of rampSize entries,
+// each running from black through the ramp colour to white.
+const (
+	numRamps = 4
+	rampSize = 256 / numRamps
+)
+
+// faceDepth is a visible face queued for painter's-order drawing.
+type faceDepth struct {
+	face int
+	z    float64
+}
+
+// Vector is a spinning 3D object: a mesh transformed, backface-culled,
+// depth-sorted (painter's algorithm) and filled with flat or Gouraud shading
+// into palette ramps.
+//
+// Cue params: "mesh" ("cube", "torus", "icosphere" or an OBJ path),
+// "shading" ("flat" or "gouraud"), "ramps" (up to 4 "#rrggbb" colours),
+// "rot_x"/"rot_y"/"rot_z" (a number is a spin rate in radians per second,
+// keyframes give absolute angles), "scale" (a number is the base size, which
+// pulses on the beat; keyframes set it exactly) and "distance".
+type Vector struct {
+	meshName string
+	mesh     *Mesh
+	gouraud  bool
+	pal      vga.Palette
+	rot      [3]motion
+	scale    motion
+	distance float64
+	pulse    float64
+
+	// Per-frame buffers, sized to the mesh.
+	cam   []Vec3
+	proj  []vga.Vertex
+	light []float64
+	order []faceDepth
+	poly  []vga.Vertex
+}
+
+func NewVector() *Vector {
+	v := &Vector{}
+	v.Configure(nil)
+	return v
+}
+
+var defaultRamps = []color.RGBA{
+	{255, 64, 32, 255},
+	{32, 128, 255, 255},
+	{64, 224, 96, 255},
+	{255, 200, 32, 255},
+}
+
+// Configure applies cue params; see Configurable.
+func (v *Vector) Configure(p Params) error {
+	var gouraud bool
+	switch shading := p.String("shading", "gouraud"); shading {
+	case "flat", "gouraud":
+		gouraud = shading == "gouraud"
+	default:
+		return fmt.Errorf("shading: unknown mode %q", shading)
+	}
+
+	ramps := slices.Clone(defaultRamps)
+	if list, ok := p["ramps"].([]any); ok {
+		for i, item := range list {
+			if i == numRamps {
+				break
+			}
+			s, _ := item.(string)
+			c, err := parseColor(s)
+			if err != nil {
+				return fmt.Errorf("ramps: %w", err)
+			}
+			ramps[i] = c
+		}
+	}
+
+	var rot [3]motion
+	for i, key := range [3]string{"rot_x", "rot_y", "rot_z"} {
+		m, err := p.motion(key, [3]float64{0.7, 1.1, 0.3}[i])
+		if err != nil {
+			return err
+		}
+		rot[i] = m
+	}
+	scale, err := p.motion("scale", 1.0)
+	if err != nil {
+		return err
+	}
+
+	name := p.String("mesh", "torus")
+	if name != v.meshName || v.mesh == nil {
+		var m *Mesh
+		switch name {
+		case "cube":
+			m = NewCube()
+		case "torus":
+			m = NewTorus(1, 0.45, 24, 12)
+		case "icosphere":
+			m = NewIcosphere(2)
+		default:
+			if m, err = LoadOBJ(name); err != nil {
+				return fmt.Errorf("mesh: %w", err)
+			}
+		}
+		v.meshName, v.mesh = name, m
+	}
+
+	v.gouraud = gouraud
+	v.pal = rampPalettes(ramps)
+	v.rot = rot
+	// A plain number is the base scale rather than a rate.
+	v.scale = motion{track: scale.track, value: scale.rate}
+	v.distance = p.Float("distance", 4)
+	return nil
+}
+
+// rampPalettes lays out one ramp per colour: black, the colour held through
+// the middle of the ramp, then white highlights.
+func rampPalettes(colors []color.RGBA) vga.Palette {
+	var pal vga.Palette
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	for k, c := range colors[:numRamps] {
+		r := vga.RampPalette(black, c, c, white)
+		for i := 0; i < rampSize; i++ {
+			pal[k*rampSize+i] = r[i*255/(rampSize-1)]
+		}
+	}
+	return pal
+}
+
+func (v *Vector) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(v.pal)
+}
+
+func (v *Vector) Update(dt float64, sync music.FrameInfo) {
+	speed := 1.0
+	if sync.BPM > 0 {
+		speed = float64(sync.BPM) / 120.0
+	}
+	for i := range v.rot {
+		v.rot[i].update(dt, speed, sync)
+	}
+	if v.scale.track != nil {
+		v.scale.update(dt, speed, sync)
+	}
+	v.pulse = sync.BeatPulse()
+}
+
+// toLight is the direction towards the light in camera space: above left,
+// behind the viewer.
+var toLight = Vec3{-0.4, 0.5, -1}.Normalize()
+
+func (v *Vector) Draw(fb *vga.Framebuffer) {
+	fb.Clear(0)
+	m := v.mesh
+	if len(v.cam) != len(m.Verts) {
+		v.cam = make([]Vec3, len(m.Verts))
+		v.proj = make([]vga.Vertex, len(m.Verts))
+		v.light = make([]float64, len(m.Verts))
+		v.order = make([]faceDepth, 0, len(m.Faces))
+	}
+
+	scale := v.scale.value
+	if v.scale.track == nil {
+		scale *= 1 + 0.12*v.pulse
+	}
+	rot := RotationXYZ(v.rot[0].value, v.rot[1].value, v.rot[2].value)
+	xf := rot.Scaled(scale)
+
+	cx, cy := float64(fb.Width)/2, float64(fb.Height)/2
+	focal := 1.5 * float64(fb.Height)
+	for i, p := range m.Verts {
+		c := xf.Apply(p)
+		c.Z += v.distance
+		v.cam[i] = c
+		if c.Z > 0.01 {
+			v.proj[i] = vga.Vertex{X: cx + c.X/c.Z*focal, Y: cy - c.Y/c.Z*focal, Z: c.Z}
+		}
+		if v.gouraud {
+			v.light[i] = intensity(rot.Apply(m.vertNormals[i]))
+		}
+	}
+
+	// Cull faces pointing away from the camera (or crossing the near plane)
+	// and sort the rest back to front.
+	v.order = v.order[:0]
+	for fi, f := range m.Faces {
+		if rot.Apply(m.faceNormals[fi]).Dot(v.cam[f.V[0]]) >= 0 {
+			continue
+		}
+		z := 0.0
+		near := false
+		for _, i := range f.V {
+			near = near || v.cam[i].Z <= 0.01
+			z += v.cam[i].Z
+		}
+		if !near {
+			v.order = append(v.order, faceDepth{fi, z / float64(len(f.V))})
+		}
+	}
+	slices.SortFunc(v.order, func(a, b faceDepth) int {
+		switch {
+		case a.z > b.z:
+			return -1
+		case a.z < b.z:
+			return 1
+		}
+		return 0
+	})
+
+	for _, fd := range v.order {
+		f := m.Faces[fd.face]
+		base := float64((f.Ramp % numRamps) * rampSize)
+		v.poly = v.poly[:0]
+		for _, i := range f.V {
+			pv := v.proj[i]
+			pv.Shade = base + v.light[i]*(rampSize-1)
+			v.poly = append(v.poly, pv)
+		}
+		if v.gouraud {
+			fb.FillPolygonGouraud(v.poly)
+		} else {
+			shade := base + intensity(rot.Apply(m.faceNormals[fd.face]))*(rampSize-1)
+			fb.FillPolygon(v.poly, byte(shade))
+		}
+	}
+}
+
+// intensity is the diffuse light level (0-1) for a camera-space normal,
+// with a little ambient so unlit sides stay visible.
+func intensity(n Vec3) float64 {
+	return 0.12 + 0.88*math.Max(0, n.Dot(toLight))
+}
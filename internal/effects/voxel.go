@@ -0,0 +1,332 @@
+package effects
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// Generated terrain palette: fogLevels copies of a terrain ramp of
+// terrainShades colours (4 materials x 8 brightness levels), each copy
+// blended further towards the fog colour.
+const (
+	fogLevels     = 8
+	terrainShades = 256 / fogLevels
+	voxelMapSize  = 512
+)
+
+// terrainColors are the generated map's materials, low to high.
+var terrainColors = [4]color.RGBA{
+	{24, 64, 160, 255},   // water
+	{60, 144, 48, 255},   // grass
+	{128, 104, 80, 255},  // rock
+	{240, 240, 248, 255}, // snow
+}
+
+// Voxel is a Comanche-style voxel landscape: a height map and colour map
+// rendered as vertical columns front to back with a per-column occlusion
+// buffer, fogging into the palette with distance.
+//
+// Cue params: "heightmap" and "colormap" (PNG paths; otherwise the terrain
+// is generated with diamond-square from "seed" and "roughness"), "fog"
+// ("#rrggbb"), "speed", "altitude" (height above the ground), "pitch"
+// (horizon shift as a fraction of screen height) and "yaw" (a number is a
+// turn rate, keyframes give the heading).
+type Voxel struct {
+	mapKey     string // settings the maps were built from
+	mapW, mapH int
+	height     []byte
+	color      []byte
+	pal        vga.Palette
+	fog        [fogLevels][256]byte
+	sky        byte
+
+	speed    float64
+	altitude motion
+	pitch    motion
+	yaw      motion
+	camX     float64
+	camY     float64
+	camZ     float64
+
+	ybuf []int
+}
+
+func NewVoxel() *Voxel {
+	v := &Voxel{}
+	v.Configure(nil)
+	return v
+}
+
+// Configure applies cue params; see Configurable.
+func (v *Voxel) Configure(p Params) error {
+	fogCol := color.RGBA{128, 152, 192, 255}
+	if s := p.String("fog", ""); s != "" {
+		c, err := parseColor(s)
+		if err != nil {
+			return fmt.Errorf("fog: %w", err)
+		}
+		fogCol = c
+	}
+
+	altitude, err := p.motion("altitude", 60)
+	if err != nil {
+		return err
+	}
+	pitch, err := p.motion("pitch", 0)
+	if err != nil {
+		return err
+	}
+	yaw, err := p.motion("yaw", 0.12)
+	if err != nil {
+		return err
+	}
+
+	// Rebuilding the maps is slow, so skip it when a cue only changes the
+	// camera.
+	key := fmt.Sprint(p["heightmap"], p["colormap"], p["seed"], p["roughness"], fogCol)
+	if key != v.mapKey {
+		if err := v.buildMaps(p, fogCol); err != nil {
+			return err
+		}
+		v.mapKey = key
+	}
+
+	v.speed = p.Float("speed", 60)
+	v.altitude, v.pitch, v.yaw = altitude, pitch, yaw
+	// Altitude and pitch numbers are values, not rates.
+	if v.altitude.track == nil {
+		v.altitude.value = v.altitude.rate
+	}
+	if v.pitch.track == nil {
+		v.pitch.value = v.pitch.rate
+	}
+	return nil
+}
+
+// buildMaps loads or generates the height and colour maps and the palette
+// and fog table that go with them. Nothing changes unless all of it
+// succeeds.
+func (v *Voxel) buildMaps(p Params, fogCol color.RGBA) error {
+	var (
+		w, h         int
+		height, cmap []byte
+		pal          vga.Palette
+		fog          [fogLevels][256]byte
+		sky          byte
+	)
+	if path := p.String("heightmap", ""); path != "" {
+		s, _, err := vga.LoadSpritePNG(path)
+		if err != nil {
+			return fmt.Errorf("heightmap: %w", err)
+		}
+		w, h, height = s.Width, s.Height, s.Pixels
+	} else {
+		rng := rand.New(rand.NewSource(int64(p.Int("seed", 1))))
+		w, h = voxelMapSize, voxelMapSize
+		height = diamondSquare(voxelMapSize, p.Float("roughness", 0.55), rng)
+	}
+
+	if path := p.String("colormap", ""); path != "" {
+		s, spal, err := vga.LoadSpritePNG(path)
+		if err != nil {
+			return fmt.Errorf("colormap: %w", err)
+		}
+		if s.Width != w || s.Height != h {
+			return fmt.Errorf("colormap: size %dx%d does not match heightmap %dx%d",
+				s.Width, s.Height, w, h)
+		}
+		cmap, pal = s.Pixels, spal
+		// Arbitrary palettes fog through a nearest-colour table.
+		for l := range fog {
+			for c := range fog[l] {
+				fog[l][c] = pal.Nearest(vga.LerpColor(pal[c], fogCol, fogAmount(l)))
+			}
+		}
+		sky = pal.Nearest(fogCol)
+	} else {
+		cmap = shadeTerrain(height, w, h)
+		pal = terrainPalette(fogCol)
+		for l := range fog {
+			for c := range fog[l] {
+				fog[l][c] = byte(l*terrainShades + c%terrainShades)
+			}
+		}
+		sky = byte((fogLevels - 1) * terrainShades)
+	}
+
+	v.mapW, v.mapH, v.height, v.color = w, h, height, cmap
+	v.pal, v.fog, v.sky = pal, fog, sky
+	return nil
+}
+
+// fogAmount is how far fog level l blends towards the fog colour; the last
+// level is pure fog so the far edge melts into the sky.
+func fogAmount(l int) float64 {
+	return float64(l) / float64(fogLevels-1)
+}
+
+// terrainPalette lays out the terrain ramp at every fog level.
+func terrainPalette(fogCol color.RGBA) vga.Palette {
+	var pal vga.Palette
+	for l := 0; l < fogLevels; l++ {
+		for m, c := range terrainColors {
+			for b := 0; b < 8; b++ {
+				lit := vga.LerpColor(color.RGBA{0, 0, 0, 255}, c, 0.3+0.7*float64(b)/7)
+				pal[l*terrainShades+m*8+b] = vga.LerpColor(lit, fogCol, fogAmount(l))
+			}
+		}
+	}
+	return pal
+}
+
+// diamondSquare generates an n x n (n a power of two) height map that tiles
+// seamlessly. Each halving of the step scales the random offsets by rough.
+func diamondSquare(n int, rough float64, rng *rand.Rand) []byte {
+	h := make([]float64, n*n)
+	at := func(x, y int) *float64 { return &h[(y&(n-1))*n+x&(n-1)] }
+	scale := 1.0
+	for step := n; step > 1; step /= 2 {
+		half := step / 2
+		for y := 0; y < n; y += step {
+			for x := 0; x < n; x += step {
+				avg := (*at(x, y) + *at(x+step, y) + *at(x, y+step) + *at(x+step, y+step)) / 4
+				*at(x+half, y+half) = avg + (rng.Float64()*2-1)*scale
+			}
+		}
+		for y := 0; y < n; y += half {
+			for x := (y/half + 1) % 2 * half; x < n; x += step {
+				avg := (*at(x-half, y) + *at(x+half, y) + *at(x, y-half) + *at(x, y+half)) / 4
+				*at(x, y) = avg + (rng.Float64()*2-1)*scale
+			}
+		}
+		scale *= rough
+	}
+
+	lo, hi := h[0], h[0]
+	for _, v := range h {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	out := make([]byte, n*n)
+	for i, v := range h {
+		out[i] = byte((v - lo) / math.Max(hi-lo, 1e-9) * 255)
+	}
+	return out
+}
+
+// shadeTerrain colours a height map: material by height, brightness from
+// the slope facing the light. Water is flattened to its surface.
+func shadeTerrain(height []byte, w, h int) []byte {
+	const water = 80
+	out := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			hv := int(height[i])
+			slope := int(height[y*w+wrapTexel(x-1, w)]) - int(height[y*w+wrapTexel(x+1, w)]) +
+				int(height[wrapTexel(y-1, h)*w+x]) - int(height[wrapTexel(y+1, h)*w+x])
+			var m, b int
+			switch {
+			case hv < water:
+				m, b = 0, 7-(water-hv)/12
+			case hv < 150:
+				m = 1
+			case hv < 205:
+				m = 2
+			default:
+				m = 3
+			}
+			if m > 0 {
+				b = 4 + slope/3
+			}
+			out[i] = byte(m*8 + min(max(b, 0), 7))
+		}
+	}
+	for i, hv := range height {
+		if hv < water {
+			height[i] = water
+		}
+	}
+	return out
+}
+
+func (v *Voxel) Init(fb *vga.Framebuffer) {
+	fb.SetPalette(v.pal)
+}
+
+func (v *Voxel) Update(dt float64, sync music.FrameInfo) {
+	tempo := 1.0
+	if sync.BPM > 0 {
+		tempo = float64(sync.BPM) / 120.0
+	}
+	v.yaw.update(dt, tempo, sync)
+	if v.altitude.track != nil {
+		v.altitude.update(dt, tempo, sync)
+	}
+	if v.pitch.track != nil {
+		v.pitch.update(dt, tempo, sync)
+	}
+
+	step := v.speed * tempo * dt
+	v.camX += math.Cos(v.yaw.value) * step
+	v.camY += math.Sin(v.yaw.value) * step
+
+	// Follow the ground smoothly rather than jumping over every ridge.
+	ground := float64(v.heightAt(v.camX, v.camY))
+	target := ground + v.altitude.value
+	if v.camZ == 0 {
+		v.camZ = target
+	}
+	v.camZ += (target - v.camZ) * math.Min(1, dt*2)
+	v.camZ = math.Max(v.camZ, ground+10)
+}
+
+func (v *Voxel) heightAt(x, y float64) byte {
+	return v.height[wrapTexel(int(y), v.mapH)*v.mapW+wrapTexel(int(x), v.mapW)]
+}
+
+func (v *Voxel) Draw(fb *vga.Framebuffer) {
+	fb.Clear(v.sky)
+	w, h := fb.Width, fb.Height
+	if len(v.ybuf) != w {
+		v.ybuf = make([]int, w)
+	}
+	for x := range v.ybuf {
+		v.ybuf[x] = h
+	}
+
+	const maxDist = 600.0
+	horizon := float64(h) * (0.35 + v.pitch.value)
+	proj := float64(w) / 2 // 90 degree field of view
+	sin, cos := math.Sincos(v.yaw.value)
+
+	// March depth slices front to back; each column only draws what rises
+	// above everything nearer (the occlusion buffer ybuf).
+	dz := 1.0
+	for z := 1.0; z < maxDist; z += dz {
+		// Left and right ends of this slice, stepped across the columns.
+		lx := v.camX + cos*z + sin*z
+		ly := v.camY + sin*z - cos*z
+		stepX := -2 * sin * z / float64(w)
+		stepY := 2 * cos * z / float64(w)
+		fog := &v.fog[min(int(z/maxDist*fogLevels), fogLevels-1)]
+		invZ := proj / z
+
+		for x := 0; x < w; x++ {
+			mx, my := wrapTexel(int(lx), v.mapW), wrapTexel(int(ly), v.mapH)
+			i := my*v.mapW + mx
+			top := int((v.camZ-float64(v.height[i]))*invZ + horizon)
+			if top < v.ybuf[x] {
+				fb.VLine(x, top, v.ybuf[x]-1, fog[v.color[i]])
+				v.ybuf[x] = top
+			}
+			lx += stepX
+			ly += stepY
+		}
+		dz *= 1.015 // coarser steps in the distance
+	}
+}
@@ -0,0 +1,179 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// waterStep is the fixed simulation step, so ripples spread at the same
+// speed whatever the frame rate (and identically in offline renders).
+const waterStep = 1.0 / 60
+
+// waterDrop is a pending disturbance at a screen position (as fractions).
+type waterDrop struct {
+	x, y     float64
+	strength int
+}
+
+// Water is the classic two-buffer ripple simulation. Each step a cell
+// becomes half the sum of its neighbours minus its previous value, then
+// loses a little energy; the slope of the surface displaces where each
+// pixel is read from the source image, giving refraction.
+//
+// Drops fall on every beat and when a channel plays a note (its volume
+// jumps). Water is a Filter; standalone, its source is set by the cue.
+//
+// Cue params: "source" ("image" or "previous": a snapshot of the frame on
+// screen when the cue starts), "image" (see Params.Texture, tiled), "drops"
+// ("beat", "notes" or "both"), "rain" (extra random drops per second),
+// "damping" (fraction of energy lost per step), "refraction" and "seed".
+type Water struct {
+	source     filterSource
+	beatDrops  bool
+	noteDrops  bool
+	rain       float64
+	damping    int // energy lost per step, in 1/1024ths
+	refraction int // displacement scale, 8.8 fixed point
+	seed       int64
+
+	w, h     int
+	cur, old []int16
+
+	rng     *rand.Rand
+	pending []waterDrop
+	steps   float64
+	lastRow int
+	notes   noteTracker
+}
+
+func NewWater() *Water {
+	w := &Water{}
+	w.Configure(nil)
+	return w
+}
+
+// Configure applies cue params; see Configurable.
+func (w *Water) Configure(p Params) error {
+	drops := p.String("drops", "both")
+	switch drops {
+	case "beat", "notes", "both":
+	default:
+		return fmt.Errorf("drops: unknown mode %q", drops)
+	}
+	// The source changes nothing unless it succeeds, so it goes last.
+	if err := w.source.configure(p); err != nil {
+		return err
+	}
+
+	w.beatDrops = drops != "notes"
+	w.noteDrops = drops != "beat"
+	w.rain = p.Float("rain", 0)
+	w.damping = int(p.Float("damping", 0.03) * 1024)
+	w.refraction = int(p.Float("refraction", 1) * 256)
+	w.seed = int64(p.Int("seed", 1))
+	return nil
+}
+
+func (w *Water) Init(fb *vga.Framebuffer) {
+	w.rng = rand.New(rand.NewSource(w.seed))
+	w.pending = w.pending[:0]
+	w.steps = 0
+	w.lastRow = -1
+	w.w = 0 // restart with calm water
+	w.resize(fb)
+	w.source.init(fb)
+}
+
+// resize (re)allocates the height buffers for fb.
+func (w *Water) resize(fb *vga.Framebuffer) {
+	if w.w == fb.Width && w.h == fb.Height {
+		return
+	}
+	w.w, w.h = fb.Width, fb.Height
+	w.cur = make([]int16, w.w*w.h)
+	w.old = make([]int16, w.w*w.h)
+}
+
+func (w *Water) Update(dt float64, sync music.FrameInfo) {
+	if w.rng == nil {
+		w.rng = rand.New(rand.NewSource(w.seed))
+	}
+	w.steps += dt / waterStep
+
+	if w.beatDrops && sync.Row != w.lastRow && sync.Row%4 == 0 {
+		w.pending = append(w.pending, waterDrop{0.2 + 0.6*w.rng.Float64(), 0.2 + 0.6*w.rng.Float64(), 900})
+	}
+	w.lastRow = sync.Row
+	w.notes.scan(sync, func(ch, vol int) {
+		if w.noteDrops {
+			// Each channel drops along its own column of the screen.
+			x := (float64(ch) + 0.5) / float64(sync.NumChannels)
+			w.pending = append(w.pending, waterDrop{x, 0.1 + 0.8*w.rng.Float64(), 2 * vol})
+		}
+	})
+	for n := w.rain * dt; n > 0; n-- {
+		if n >= 1 || w.rng.Float64() < n {
+			w.pending = append(w.pending, waterDrop{w.rng.Float64(), w.rng.Float64(), 300})
+		}
+	}
+}
+
+// simulate injects pending drops and runs the whole simulation steps due.
+func (w *Water) simulate() {
+	r := max(3*w.w/320, 1)
+	for _, d := range w.pending {
+		cx, cy := int(d.x*float64(w.w)), int(d.y*float64(w.h))
+		for y := max(cy-r, 1); y <= min(cy+r, w.h-2); y++ {
+			for x := max(cx-r, 1); x <= min(cx+r, w.w-2); x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r {
+					w.cur[y*w.w+x] -= int16(d.strength)
+				}
+			}
+		}
+	}
+	w.pending = w.pending[:0]
+
+	for ; w.steps >= 1; w.steps-- {
+		// old becomes the new state; edges stay still.
+		for y := 1; y < w.h-1; y++ {
+			for x := 1; x < w.w-1; x++ {
+				i := y*w.w + x
+				v := (int(w.cur[i-1])+int(w.cur[i+1])+int(w.cur[i-w.w])+int(w.cur[i+w.w]))>>1 - int(w.old[i])
+				v -= v * w.damping >> 10
+				w.old[i] = int16(max(min(v, math.MaxInt16), math.MinInt16))
+			}
+		}
+		w.cur, w.old = w.old, w.cur
+	}
+}
+
+func (w *Water) Draw(fb *vga.Framebuffer) {
+	if !w.source.ready(fb) {
+		w.Init(fb)
+	}
+	w.DrawFrom(fb, w.source.fb)
+}
+
+// DrawFrom refracts src through the water surface into dst; see Filter.
+func (w *Water) DrawFrom(dst, src *vga.Framebuffer) {
+	w.resize(dst)
+	w.simulate()
+	for y := 0; y < w.h; y++ {
+		row := dst.Pixels[y*dst.Stride : y*dst.Stride+w.w]
+		for x := range row {
+			ox, oy := 0, 0
+			if x > 0 && x < w.w-1 && y > 0 && y < w.h-1 {
+				i := y*w.w + x
+				ox = (int(w.cur[i-1]) - int(w.cur[i+1])) * w.refraction >> 11
+				oy = (int(w.cur[i-w.w]) - int(w.cur[i+w.w])) * w.refraction >> 11
+			}
+			sx := min(max(x+ox, 0), w.w-1)
+			sy := min(max(y+oy, 0), w.h-1)
+			row[x] = src.Pixels[sy*src.Stride+sx]
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package music
+
+// BeatPulse returns a 0.0-1.0 value that peaks at 1.0 on each beat (row 0 of each beat)
+// and decays to 0.0 by the next beat. Useful for reactive visuals.
+func (info FrameInfo) BeatPulse() float64 {
+	if info.Speed <= 0 {
+		return 0
+	}
+	return 1.0 - info.BeatProgress
+}
+
+// RowPulse returns 1.0 on the first frame of each row, 0.0 otherwise.
+func (info FrameInfo) RowPulse() float64 {
+	if info.Frame == 0 {
+		return 1.0
+	}
+	return 0.0
+}
+
+// MaxChannelVolume returns the highest volume across all active channels (0.0-1.0).
+func (info FrameInfo) MaxChannelVolume() float64 {
+	max := 0
+	for i := 0; i < info.NumChannels; i++ {
+		if info.ChannelVol[i] > max {
+			max = info.ChannelVol[i]
+		}
+	}
+	return float64(max) / 255.0
+}
@@ -17,6 +17,7 @@ type CueDef struct {
 	Effect      string `json:"effect"`
 	Transition  string `json:"transition"`
 	FadeDur     float64 `json:"fade_dur"`
+	Params      map[string]any `json:"params"` // effect settings, see effects.Configurable
 }
 
 func LoadCueFile(path string) (*Timeline, error) {
@@ -54,6 +55,7 @@ func LoadCueFile(path string) (*Timeline, error) {
 			EffectIdx: idx,
 			Transition: transition,
 			FadeDur:   fadeDur,
+			Params:    cd.Params,
 		}
 	}
 
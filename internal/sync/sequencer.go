@@ -1,6 +1,9 @@
 package sync
 
 import (
+	"fmt"
+	"log"
+
 	"github.com/holden/vga-go/internal/effects"
 	"github.com/holden/vga-go/internal/music"
 	"github.com/holden/vga-go/internal/vga"
@@ -20,6 +23,8 @@ type Sequencer struct {
 	fading     bool
 
 	initialized map[int]bool // tracks which effects have been Init'd
+
+	prevFB *vga.Framebuffer // scratch buffer the outgoing effect draws into during a fade
 }
 
 // NewSequencer creates a sequencer with the given effects and timeline.
@@ -44,15 +49,30 @@ func (s *Sequencer) Update(dt float64, info music.FrameInfo, fb *vga.Framebuffer
 		s.currentIdx = cueIdx
 
 		newEffect := cue.EffectIdx
-		if newEffect != s.activeIdx && newEffect >= 0 && newEffect < len(s.effects) {
-			// Initialize if first time
-			if !s.initialized[newEffect] {
+		active := newEffect == s.activeIdx
+		// A cue without params for the running effect keeps its settings;
+		// anything else reconfigures, and new settings are applied by Init
+		if !(active && cue.Params == nil) && s.configure(newEffect, cue.Params) {
+			s.initialized[newEffect] = false
+			if active {
 				s.effects[newEffect].Init(fb)
 				s.initialized[newEffect] = true
 			}
+		}
+		if !active && newEffect >= 0 && newEffect < len(s.effects) {
+			// Text and scrolling set up by the outgoing effect do not carry over
+			if fb.Text != nil {
+				fb.Text.Clear(0)
+			}
+			fb.ResetScroll()
 
 			switch cue.Transition {
 			case "fade", "crossfade":
+				// Initialize if first time
+				if !s.initialized[newEffect] {
+					s.effects[newEffect].Init(fb)
+					s.initialized[newEffect] = true
+				}
 				s.prevIdx = s.activeIdx
 				s.activeIdx = newEffect
 				s.fadeDur = cue.FadeDur
@@ -65,6 +85,7 @@ func (s *Sequencer) Update(dt float64, info music.FrameInfo, fb *vga.Framebuffer
 				s.fadeAlpha = 1.0
 				// Apply the new effect's palette immediately
 				s.effects[newEffect].Init(fb)
+				s.initialized[newEffect] = true
 			}
 		}
 	}
@@ -94,6 +115,9 @@ func (s *Sequencer) Update(dt float64, info music.FrameInfo, fb *vga.Framebuffer
 
 // Draw renders the current effect(s) into the framebuffer.
 func (s *Sequencer) Draw(fb *vga.Framebuffer) {
+	// Raster hooks are per-frame state; the active effect re-registers them in Draw
+	fb.ResetRaster()
+
 	if !s.fading {
 		// Simple case: just draw the active effect
 		if s.activeIdx >= 0 && s.activeIdx < len(s.effects) {
@@ -103,23 +127,25 @@ func (s *Sequencer) Draw(fb *vga.Framebuffer) {
 	}
 
 	// Crossfade: blend two framebuffers
-	var prevFB vga.Framebuffer
+	if s.prevFB == nil || s.prevFB.Width != fb.Width || s.prevFB.Height != fb.Height {
+		mode := fb.Mode
+		mode.Width, mode.Height = fb.Width, fb.Height
+		s.prevFB = vga.NewFramebufferMode(mode, fb.Palette)
+	}
+	prevFB := s.prevFB
 	prevFB.Palette = fb.Palette
 
 	if s.prevIdx >= 0 && s.prevIdx < len(s.effects) {
-		s.effects[s.prevIdx].Draw(&prevFB)
+		s.effects[s.prevIdx].Draw(prevFB)
 	}
 	if s.activeIdx >= 0 && s.activeIdx < len(s.effects) {
 		s.effects[s.activeIdx].Draw(fb)
 	}
 
 	// Alpha blend at the palette index level (simple: pick based on alpha threshold)
-	alpha := s.fadeAlpha
-	for i := range fb.Pixels {
-		if alpha < 0.5 {
-			fb.Pixels[i] = prevFB.Pixels[i]
-		}
-		// When alpha >= 0.5, keep fb.Pixels[i] (new effect) as-is
+	// When alpha >= 0.5, keep fb.Pixels (new effect) as-is
+	if s.fadeAlpha < 0.5 {
+		fb.CopyFrom(prevFB)
 	}
 }
 
@@ -131,7 +157,47 @@ func (s *Sequencer) InitFirst(fb *vga.Framebuffer) {
 			idx = s.timeline.Cues[0].EffectIdx
 		}
 		s.activeIdx = idx
+		if len(s.timeline.Cues) > 0 {
+			s.configure(idx, s.timeline.Cues[0].Params)
+			s.currentIdx = 0 // applied; the first Update must not apply it again
+		}
 		s.effects[idx].Init(fb)
 		s.initialized[idx] = true
 	}
 }
+
+// configure passes a cue's params to its effect if the effect is
+// Configurable, reporting whether it was. Errors were already reported by
+// CheckParams, so they are only logged here.
+func (s *Sequencer) configure(idx int, p effects.Params) bool {
+	if idx < 0 || idx >= len(s.effects) {
+		return false
+	}
+	c, ok := s.effects[idx].(effects.Configurable)
+	if !ok {
+		return false
+	}
+	if err := c.Configure(p); err != nil {
+		log.Printf("[sync] effect %d: %v", idx, err)
+	}
+	return true
+}
+
+// CheckParams configures each cue's effect with its params once, so bad
+// settings (unknown palettes, missing textures) are reported at startup
+// rather than when the cue is reached.
+func (s *Sequencer) CheckParams() error {
+	for i, cue := range s.timeline.Cues {
+		if cue.Params == nil || cue.EffectIdx < 0 || cue.EffectIdx >= len(s.effects) {
+			continue
+		}
+		c, ok := s.effects[cue.EffectIdx].(effects.Configurable)
+		if !ok {
+			return fmt.Errorf("cue %d: effect %d does not take params", i, cue.EffectIdx)
+		}
+		if err := c.Configure(cue.Params); err != nil {
+			return fmt.Errorf("cue %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/holden/vga-go/internal/effects"
+	"github.com/holden/vga-go/internal/music"
+	"github.com/holden/vga-go/internal/vga"
+)
+
+// countingEffect records the calls the sequencer makes.
+type countingEffect struct {
+	inits, configures int
+	params            effects.Params
+}
+
+func (e *countingEffect) Init(*vga.Framebuffer)           { e.inits++ }
+func (e *countingEffect) Update(float64, music.FrameInfo) {}
+func (e *countingEffect) Draw(*vga.Framebuffer)           {}
+func (e *countingEffect) Configure(p effects.Params) error {
+	e.configures++
+	e.params = p
+	return nil
+}
+
+func TestSequencerCues(t *testing.T) {
+	a, b := &countingEffect{}, &countingEffect{}
+	tl := NewTimeline([]Cue{
+		{Pos: Position{Order: 0}, EffectIdx: 0, Transition: "cut"},
+		{Pos: Position{Order: 1}, EffectIdx: 0, Transition: "cut", Params: effects.Params{"speed": 2.0}},
+		{Pos: Position{Order: 2}, EffectIdx: 0, Transition: "cut"},
+		{Pos: Position{Order: 3}, EffectIdx: 1, Transition: "cut"},
+		{Pos: Position{Order: 4}, EffectIdx: 0, Transition: "fade", FadeDur: 1},
+	})
+	fb := vga.NewFramebuffer(vga.DefaultPalette())
+	s := NewSequencer([]effects.Effect{a, b}, tl)
+	s.InitFirst(fb)
+
+	tests := []struct {
+		order            int
+		aInits, bInits   int
+		aConfig, bConfig int
+	}{
+		{0, 1, 0, 1, 0}, // InitFirst, then the first cue for the running effect
+		{1, 2, 0, 2, 0}, // new params for the running effect: re-Init
+		{2, 2, 0, 2, 0}, // no params for the running effect: left alone
+		{3, 2, 1, 2, 1}, // cut to a new effect: one Init
+		{4, 3, 1, 3, 1}, // back with defaults, fading: Init with them
+	}
+	for _, tt := range tests {
+		s.Update(1.0/60, music.FrameInfo{Order: tt.order}, fb)
+		if a.inits != tt.aInits || b.inits != tt.bInits {
+			t.Errorf("order %d: inits a=%d b=%d, want %d %d", tt.order, a.inits, b.inits, tt.aInits, tt.bInits)
+		}
+		if a.configures != tt.aConfig || b.configures != tt.bConfig {
+			t.Errorf("order %d: configures a=%d b=%d, want %d %d", tt.order, a.configures, b.configures, tt.aConfig, tt.bConfig)
+		}
+	}
+	if a.params != nil {
+		t.Errorf("a configured with %v, want defaults", a.params)
+	}
+}
+
+// Starting up configures and Inits the first cue's effect exactly once.
+func TestSequencerInitFirst(t *testing.T) {
+	e := &countingEffect{}
+	tl := NewTimeline([]Cue{
+		{Pos: Position{Order: 0}, EffectIdx: 0, Transition: "cut", Params: effects.Params{"speed": 2.0}},
+	})
+	fb := vga.NewFramebuffer(vga.DefaultPalette())
+	s := NewSequencer([]effects.Effect{e}, tl)
+	s.InitFirst(fb)
+	for i := 0; i < 3; i++ {
+		s.Update(1.0/60, music.FrameInfo{}, fb)
+	}
+	if e.configures != 1 || e.inits != 1 {
+		t.Errorf("configures %d, inits %d, want 1 1", e.configures, e.inits)
+	}
+}
+
+func TestSequencerResetsScroll(t *testing.T) {
+	tl := NewTimeline([]Cue{
+		{Pos: Position{Order: 0}, EffectIdx: 0, Transition: "cut"},
+		{Pos: Position{Order: 1}, EffectIdx: 1, Transition: "cut"},
+	})
+	fb := vga.NewFramebuffer(vga.DefaultPalette())
+	s := NewSequencer([]effects.Effect{&countingEffect{}, &countingEffect{}}, tl)
+	s.InitFirst(fb)
+	if err := fb.SetPages(2); err != nil {
+		t.Fatal(err)
+	}
+	fb.ShowPage(1)
+	s.Update(1.0/60, music.FrameInfo{Order: 1}, fb)
+	if w, h := fb.VirtualSize(); fb.StartAddr != 0 || w != fb.Width || h != fb.Height {
+		t.Errorf("after an effect change: start %d, virtual size %dx%d", fb.StartAddr, w, h)
+	}
+}
@@ -1,6 +1,9 @@
 package sync
 
-import "github.com/holden/vga-go/internal/music"
+import (
+	"github.com/holden/vga-go/internal/effects"
+	"github.com/holden/vga-go/internal/music"
+)
 
 // Position identifies a point in the tracker timeline.
 type Position struct {
@@ -14,6 +17,7 @@ type Cue struct {
 	EffectIdx int    // Index of the effect to activate
 	Transition string // "cut", "fade", "crossfade"
 	FadeDur   float64 // Duration of fade in seconds (for fade/crossfade)
+	Params    effects.Params // Settings passed to Configurable effects (nil = defaults)
 }
 
 // Timeline holds the ordered list of cues for the demo.
@@ -42,27 +46,15 @@ func (t *Timeline) ActiveCue(info music.FrameInfo) int {
 // BeatPulse returns a 0.0-1.0 value that peaks at 1.0 on each beat (row 0 of each beat)
 // and decays to 0.0 by the next beat. Useful for reactive visuals.
 func BeatPulse(info music.FrameInfo) float64 {
-	if info.Speed <= 0 {
-		return 0
-	}
-	return 1.0 - info.BeatProgress
+	return info.BeatPulse()
 }
 
 // RowPulse returns 1.0 on the first frame of each row, 0.0 otherwise.
 func RowPulse(info music.FrameInfo) float64 {
-	if info.Frame == 0 {
-		return 1.0
-	}
-	return 0.0
+	return info.RowPulse()
 }
 
 // MaxChannelVolume returns the highest volume across all active channels (0.0-1.0).
 func MaxChannelVolume(info music.FrameInfo) float64 {
-	max := 0
-	for i := 0; i < info.NumChannels; i++ {
-		if info.ChannelVol[i] > max {
-			max = info.ChannelVol[i]
-		}
-	}
-	return float64(max) / 255.0
+	return info.MaxChannelVolume()
 }
@@ -0,0 +1,127 @@
+package vga
+
+import "image/color"
+
+// BlendTable is a 256x256 translucency lookup table: Table[src][dst] is the
+// palette index closest to src blended over dst. Indexed-colour demos use
+// these for glass, shadows and additive glows without leaving 8-bit mode.
+type BlendTable [256][256]byte
+
+// BlendFunc combines a source colour drawn over a destination colour.
+type BlendFunc func(src, dst color.RGBA) color.RGBA
+
+// NewBlendTable builds a table for pal by evaluating fn for every index pair
+// and matching the result back to the nearest palette entry.
+func NewBlendTable(pal Palette, fn BlendFunc) *BlendTable {
+	m := newNearestMatcher(&pal)
+	t := &BlendTable{}
+	for s := 0; s < 256; s++ {
+		for d := 0; d < 256; d++ {
+			t[s][d] = m.match(fn(pal[s], pal[d]))
+		}
+	}
+	return t
+}
+
+// AdditiveTable saturates src + dst per channel.
+func AdditiveTable(pal Palette) *BlendTable {
+	return NewBlendTable(pal, func(s, d color.RGBA) color.RGBA {
+		return color.RGBA{sat(int(s.R) + int(d.R)), sat(int(s.G) + int(d.G)), sat(int(s.B) + int(d.B)), 255}
+	})
+}
+
+// AverageTable mixes src and dst 50/50, e.g. for glass logos.
+func AverageTable(pal Palette) *BlendTable {
+	return NewBlendTable(pal, func(s, d color.RGBA) color.RGBA {
+		return color.RGBA{uint8((int(s.R) + int(d.R)) / 2), uint8((int(s.G) + int(d.G)) / 2), uint8((int(s.B) + int(d.B)) / 2), 255}
+	})
+}
+
+// MultiplyTable multiplies src and dst per channel.
+func MultiplyTable(pal Palette) *BlendTable {
+	return NewBlendTable(pal, func(s, d color.RGBA) color.RGBA {
+		return color.RGBA{uint8(int(s.R) * int(d.R) / 255), uint8(int(s.G) * int(d.G) / 255), uint8(int(s.B) * int(d.B) / 255), 255}
+	})
+}
+
+// ShadowTable darkens dst by factor (0.0-1.0) wherever a source pixel lands,
+// regardless of the source colour.
+func ShadowTable(pal Palette, factor float64) *BlendTable {
+	k := 1.0 - factor
+	return NewBlendTable(pal, func(_, d color.RGBA) color.RGBA {
+		return color.RGBA{uint8(float64(d.R) * k), uint8(float64(d.G) * k), uint8(float64(d.B) * k), 255}
+	})
+}
+
+func sat(v int) uint8 {
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// Nearest returns the palette index whose colour is closest to c.
+func (p *Palette) Nearest(c color.RGBA) byte {
+	best := 0
+	bestDist := 1 << 30
+	for i := range p {
+		dr := int(p[i].R) - int(c.R)
+		dg := int(p[i].G) - int(c.G)
+		db := int(p[i].B) - int(c.B)
+		dist := dr*dr + dg*dg + db*db
+		if dist < bestDist {
+			best, bestDist = i, dist
+			if dist == 0 {
+				break
+			}
+		}
+	}
+	return byte(best)
+}
+
+// nearestMatcher memoises Nearest per exact colour. Blend results repeat
+// heavily (most tables produce a few thousand distinct colours), so building
+// a full table costs that many palette searches instead of 65,536.
+type nearestMatcher struct {
+	pal   *Palette
+	cache map[uint32]byte
+}
+
+func newNearestMatcher(pal *Palette) *nearestMatcher {
+	return &nearestMatcher{pal: pal, cache: make(map[uint32]byte)}
+}
+
+func (m *nearestMatcher) match(c color.RGBA) byte {
+	key := uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+	if idx, ok := m.cache[key]; ok {
+		return idx
+	}
+	idx := m.pal.Nearest(c)
+	m.cache[key] = idx
+	return idx
+}
+
+// DrawSpriteBlend draws a sprite through a blend table; index 0 stays transparent.
+func (fb *Framebuffer) DrawSpriteBlend(x, y int, s *Sprite, t *BlendTable) {
+	fb.DrawSpriteEx(x, y, s, SpriteOptions{Blend: t})
+}
+
+// HLineBlend draws a horizontal line from (x0, y) to (x1, y) through a blend table.
+func (fb *Framebuffer) HLineBlend(x0, x1, y int, colorIndex byte, t *BlendTable) {
+	x0, x1, ok := fb.clipSpan(x0, x1, y)
+	if !ok {
+		return
+	}
+	row := &t[colorIndex]
+	offset := y * fb.Stride
+	for x := x0; x <= x1; x++ {
+		fb.Pixels[offset+x] = row[fb.Pixels[offset+x]]
+	}
+}
+
+// FillRectBlend fills a rectangle through a blend table.
+func (fb *Framebuffer) FillRectBlend(x0, y0, w, h int, colorIndex byte, t *BlendTable) {
+	for y := y0; y < y0+h; y++ {
+		fb.HLineBlend(x0, x0+w-1, y, colorIndex, t)
+	}
+}
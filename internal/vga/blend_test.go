@@ -0,0 +1,91 @@
+package vga
+
+import (
+	"image/color"
+	"testing"
+)
+
+func greyRamp() Palette {
+	return GradientPalette(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255})
+}
+
+func TestBlendTables(t *testing.T) {
+	pal := greyRamp()
+	tests := []struct {
+		name     string
+		table    *BlendTable
+		src, dst byte
+		want     byte
+	}{
+		{"average of equal colours", AverageTable(pal), 101, 101, 101},
+		{"average of black and white", AverageTable(pal), 0, 255, 127},
+		{"additive with black", AdditiveTable(pal), 0, 77, 77},
+		{"additive saturates", AdditiveTable(pal), 200, 200, 255},
+		{"multiply by white", MultiplyTable(pal), 255, 42, 42},
+		{"multiply by black", MultiplyTable(pal), 0, 42, 0},
+		{"shadow halves", ShadowTable(pal, 0.5), 9, 200, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.table[tt.src][tt.dst]; got != tt.want {
+				t.Errorf("[%d][%d] = %d, want %d", tt.src, tt.dst, got, tt.want)
+			}
+		})
+	}
+}
+
+// Blending a colour with itself must give the colour back, for every entry
+// of a smooth gradient, not just a few dozen levels.
+func TestBlendIdentity(t *testing.T) {
+	pal := greyRamp()
+	avg := AverageTable(pal)
+	add := AdditiveTable(pal)
+	for i := 0; i < 256; i++ {
+		if got := avg[i][i]; got != byte(i) {
+			t.Errorf("average [%d][%d] = %d", i, i, got)
+		}
+		if got := add[i][0]; got != byte(i) {
+			t.Errorf("additive [%d][0] = %d", i, got)
+		}
+	}
+}
+
+func TestBlendSymmetry(t *testing.T) {
+	pal := DefaultPalette()
+	for name, table := range map[string]*BlendTable{
+		"average":  AverageTable(pal),
+		"additive": AdditiveTable(pal),
+		"multiply": MultiplyTable(pal),
+	} {
+		for s := 0; s < 256; s++ {
+			for d := s + 1; d < 256; d++ {
+				if table[s][d] != table[d][s] {
+					t.Fatalf("%s: [%d][%d] = %d but [%d][%d] = %d", name, s, d, table[s][d], d, s, table[d][s])
+				}
+			}
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	pal := DefaultPalette()
+	for i, c := range pal[:16] {
+		if got := pal.Nearest(c); got != byte(i) {
+			t.Errorf("Nearest(pal[%d]) = %d", i, got)
+		}
+	}
+}
+
+func TestHLineBlendClipped(t *testing.T) {
+	fb := NewFramebuffer(greyRamp())
+	fb.Clear(100)
+	fb.HLineBlend(-10, fb.Width+10, 0, 0, ShadowTable(fb.Palette, 1))
+	for x := 0; x < fb.Width; x++ {
+		if fb.Pixels[x] != 0 {
+			t.Fatalf("pixel %d = %d, want 0", x, fb.Pixels[x])
+		}
+	}
+	if fb.Pixels[fb.Stride] != 100 {
+		t.Errorf("row 1 touched")
+	}
+}
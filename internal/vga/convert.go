@@ -0,0 +1,93 @@
+package vga
+
+import (
+	"encoding/binary"
+	"image/color"
+	"runtime"
+	"sync"
+)
+
+// parallelMinPixels is the screen size from which RGBA conversion is split
+// into bands across CPUs. Mode 13h is faster converted on a single core.
+const parallelMinPixels = 320 * 400
+
+// RGBA converts the indexed framebuffer to RGBA bytes using the current palette,
+// applying the scroll registers and any raster hooks (copper list, scanline
+// func) line by line. In text modes the character buffer is drawn on top.
+// Returns a slice suitable for ebiten.Image.WritePixels().
+//
+// Conversion goes through a cache of the palette packed into 32-bit words,
+// rebuilt only when Palette has changed since the previous frame, so palette
+// animations (cycling, fades) cost one 256-entry rebuild rather than
+// per-pixel colour lookups.
+func (fb *Framebuffer) RGBA() []byte {
+	if len(fb.rgba) != fb.Width*fb.Height*4 {
+		fb.rgba = make([]byte, fb.Width*fb.Height*4)
+	}
+	fb.updatePacked()
+	switch {
+	case fb.scanline != nil || fb.copper != nil || fb.scrolled():
+		fb.rgbaScan()
+	case fb.Width*fb.Height < parallelMinPixels:
+		fb.convertRows(0, fb.Height)
+	default:
+		bands := runtime.GOMAXPROCS(0)
+		rows := (fb.Height + bands - 1) / bands
+		var wg sync.WaitGroup
+		for y0 := 0; y0 < fb.Height; y0 += rows {
+			wg.Add(1)
+			go func(y0, y1 int) {
+				defer wg.Done()
+				fb.convertRows(y0, y1)
+			}(y0, min(y0+rows, fb.Height))
+		}
+		wg.Wait()
+	}
+	if fb.Text != nil {
+		fb.overlayText()
+	}
+	return fb.rgba
+}
+
+// convertRows writes rows [y0, y1) to the RGBA buffer a word at a time.
+func (fb *Framebuffer) convertRows(y0, y1 int) {
+	packed := &fb.packed
+	for y := y0; y < y1; y++ {
+		row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+		out := fb.rgba[y*fb.Width*4 : (y+1)*fb.Width*4]
+		for x, idx := range row {
+			binary.LittleEndian.PutUint32(out[x*4:], packed[idx])
+		}
+	}
+}
+
+// updatePacked rebuilds the packed palette if Palette (or the mode's colour
+// count) changed since it was last built. Comparing the 1 KB palette catches
+// direct writes to fb.Palette as well as SetPalette/SetPaletteColor.
+func (fb *Framebuffer) updatePacked() {
+	colors := fb.Mode.Colors
+	if fb.packedOK && fb.packedColors == colors && fb.packedPal == fb.Palette {
+		return
+	}
+	packPalette(&fb.packed, &fb.Palette, colors)
+	fb.packedPal = fb.Palette
+	fb.packedColors = colors
+	fb.packedOK = true
+}
+
+// packPalette fills dst with pal packed as RGBA words. In 16-colour modes
+// each entry is first reduced to the nearest standard colour, so the
+// per-pixel loop stays a single table lookup.
+func packPalette(dst *[256]uint32, pal *Palette, colors int) {
+	for i := range dst {
+		c := pal[i]
+		if colors == 16 {
+			c = standard16[nearestStandard16(c)]
+		}
+		dst[i] = packColor(c)
+	}
+}
+
+func packColor(c color.RGBA) uint32 {
+	return uint32(c.R) | uint32(c.G)<<8 | uint32(c.B)<<16 | uint32(c.A)<<24
+}
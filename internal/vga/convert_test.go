@@ -0,0 +1,131 @@
+package vga
+
+import (
+	"image/color"
+	"testing"
+)
+
+// checkRGBA compares every converted pixel with its palette colour.
+func checkRGBA(t *testing.T, fb *Framebuffer) {
+	t.Helper()
+	out := fb.RGBA()
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			c := fb.Palette[fb.Pixels[y*fb.Stride+x]]
+			i := (y*fb.Width + x) * 4
+			if got := (color.RGBA{out[i], out[i+1], out[i+2], out[i+3]}); got != c {
+				t.Fatalf("(%d, %d) = %v, want %v", x, y, got, c)
+			}
+		}
+	}
+}
+
+func TestRGBA(t *testing.T) {
+	for _, mode := range []Mode{Mode13h, ModeX, ModeX400} { // ModeX400 converts in parallel bands
+		t.Run(mode.Name, func(t *testing.T) {
+			fb := NewFramebufferMode(mode, DefaultPalette())
+			for i := range fb.Pixels {
+				fb.Pixels[i] = byte(i * 7)
+			}
+			checkRGBA(t, fb)
+
+			// The packed cache follows SetPaletteColor and direct writes.
+			fb.SetPaletteColor(7, color.RGBA{1, 2, 3, 255})
+			checkRGBA(t, fb)
+			fb.Palette[14] = color.RGBA{4, 5, 6, 255}
+			checkRGBA(t, fb)
+			fb.SetPalette(greyRamp())
+			checkRGBA(t, fb)
+		})
+	}
+}
+
+func TestRGBA16Colours(t *testing.T) {
+	fb := NewFramebufferMode(Mode12h, greyRamp())
+	tests := []struct {
+		index byte
+		want  color.RGBA
+	}{
+		{0, standard16[0]},     // black
+		{80, standard16[8]},    // dark grey
+		{170, standard16[7]},   // light grey
+		{255, standard16[15]},  // white
+		{0x1F, standard16[0]},  // not masked to index 15 (white)
+		{0xF0, standard16[15]}, // not masked to index 0 (black)
+	}
+	for _, tt := range tests {
+		fb.Pixels[0] = tt.index
+		out := fb.RGBA()
+		if got := (color.RGBA{out[0], out[1], out[2], out[3]}); got != tt.want {
+			t.Errorf("index %d shows %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestRGBATextOverlay(t *testing.T) {
+	fb := NewFramebufferMode(ModeText80, indexPalette())
+	fb.Clear(200)
+	var block [8]byte
+	block[0] = 0x01 // top-left pixel of the glyph only
+	saved := CP437Font[1]
+	CP437Font[1] = block
+	defer func() { CP437Font[1] = saved }()
+
+	fb.Text.Put(1, 0, 1, 0x1E) // yellow on blue
+	out := fb.RGBA()
+	px := func(x, y int) color.RGBA {
+		i := (y*fb.Width + x) * 4
+		return color.RGBA{out[i], out[i+1], out[i+2], out[i+3]}
+	}
+	if got, want := px(8, 0), standard16[0x0E]; got != want {
+		t.Errorf("glyph pixel = %v, want %v", got, want)
+	}
+	if got, want := px(9, 0), standard16[0x01]; got != want {
+		t.Errorf("background pixel = %v, want %v", got, want)
+	}
+	// Cells left at attribute 0 show the graphics underneath, which in a
+	// 16-colour mode are reduced to the nearest standard colour.
+	if got, want := px(0, 0), standard16[nearestStandard16(color.RGBA{200, 0, 0, 255})]; got != want {
+		t.Errorf("transparent cell = %v, want %v", got, want)
+	}
+}
+
+func TestRGBACopper(t *testing.T) {
+	fb := NewFramebuffer(indexPalette())
+	for x := 0; x < fb.Width; x++ {
+		fb.VLine(x, 0, fb.Height-1, byte(x))
+	}
+	var c CopperList
+	c.Reset()
+	c.Gradient(100, 199, 0, color.RGBA{0, 0, 0, 255}, color.RGBA{0, 0, 198, 255})
+	c.SetScroll(150, 10)
+	c.SetScroll(160, 0)
+	fb.SetCopper(&c)
+	out := fb.RGBA()
+	at := func(x, y int) color.RGBA {
+		i := (y*fb.Width + x) * 4
+		return color.RGBA{out[i], out[i+1], out[i+2], out[i+3]}
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"above the gradient", 0, 50, color.RGBA{0, 0, 0, 255}},
+		{"gradient start", 0, 100, color.RGBA{0, 0, 0, 255}},
+		{"gradient middle", 0, 149, color.RGBA{0, 0, 98, 255}},
+		{"gradient end", 0, 199, color.RGBA{0, 0, 198, 255}},
+		{"unscrolled", 5, 149, color.RGBA{5, 0, 0, 255}},
+		{"scrolled", 5, 150, color.RGBA{15, 0, 0, 255}},
+		{"scroll reset", 5, 160, color.RGBA{5, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		if got := at(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: (%d, %d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+	// Copper writes only affect scan-out; the palette itself is untouched.
+	if fb.Palette[0] != (color.RGBA{0, 0, 0, 255}) {
+		t.Error("copper wrote to fb.Palette")
+	}
+}
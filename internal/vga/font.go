@@ -102,13 +102,13 @@ func (fb *Framebuffer) DrawChar(x, y int, ch byte, opts FontOptions) {
 			if row&(1<<uint(fx)) != 0 {
 				for sy := 0; sy < scale; sy++ {
 					for sx := 0; sx < scale; sx++ {
-						fb.SetPixel(x+fx*scale+sx, y+fy*scale+sy, 255)
+						fb.SetPixelSafe(x+fx*scale+sx, y+fy*scale+sy, 255)
 					}
 				}
 			} else if !opts.Transparent {
 				for sy := 0; sy < scale; sy++ {
 					for sx := 0; sx < scale; sx++ {
-						fb.SetPixel(x+fx*scale+sx, y+fy*scale+sy, opts.BgColor)
+						fb.SetPixelSafe(x+fx*scale+sx, y+fy*scale+sy, opts.BgColor)
 					}
 				}
 			}
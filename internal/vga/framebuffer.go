@@ -1,68 +1,184 @@
 package vga
 
 import (
+	"image"
 	"image/color"
 	"time"
 )
 
-const (
-	Width  = 320
-	Height = 200
-	Size   = Width * Height
-)
-
-// Framebuffer is a 320x200 indexed-color buffer, emulating VGA Mode 13h.
-// Each byte is a palette index (0-255).
+// Framebuffer is an indexed-color buffer emulating a VGA mode (Mode 13h,
+// 320x200, unless created with NewFramebufferMode). Each byte is a palette
+// index (0-255). Effects should size themselves from Width and Height.
+//
+// A Framebuffer may also be a view into a region of another one (see View):
+// it then shares the parent's pixel memory, addressed through Stride, and
+// has its own local coordinates and clip stack.
 type Framebuffer struct {
-	Pixels  [Size]byte
+	Pixels  []byte // Height rows of Stride bytes
 	Palette Palette
-	rgba    [Size * 4]byte // pre-allocated RGBA conversion buffer
+	Width   int
+	Height  int
+	Stride  int // bytes between the start of consecutive rows
+	Mode    Mode
+
+	// Scan-out registers (see scroll.go). The zero values display Pixels
+	// from offset 0 with no panning or split.
+	StartAddr   int // video memory offset of the top-left visible pixel
+	PixelPan    int // extra horizontal pixel shift applied to every line
+	LineCompare int // if > 0, lines from here down restart at offset 0 (split screen)
+
+	// Text is the character buffer in text modes (nil otherwise). RGBA draws
+	// it over Pixels in the standard 16 colours; cells left at attribute 0
+	// are transparent. Effects can also rasterise a TextScreen into Pixels
+	// themselves with DrawText.
+	Text *TextScreen
+
+	clip   image.Rectangle   // current clip, in local coordinates
+	clips  []image.Rectangle // saved clips for PopClip
+	parent *Framebuffer      // framebuffer a view was taken from, nil for the root
+	rgba   []byte            // lazily allocated RGBA conversion buffer
+
+	scanline ScanlineFunc // per-scanline raster hook, see SetScanlineFunc
+	copper   *CopperList  // per-scanline register writes, see SetCopper
+
+	packed       [256]uint32 // palette packed as little-endian RGBA words, see convert.go
+	packedPal    Palette     // palette packed was built from
+	packedColors int         // mode colour count packed was built for
+	packedOK     bool
 }
 
-// NewFramebuffer creates a framebuffer with the given palette.
+// NewFramebuffer creates a Mode 13h framebuffer with the given palette.
 func NewFramebuffer(pal Palette) *Framebuffer {
-	return &Framebuffer{Palette: pal}
+	return NewFramebufferMode(DefaultMode, pal)
+}
+
+// NewFramebufferMode creates a framebuffer for the given mode and palette.
+func NewFramebufferMode(mode Mode, pal Palette) *Framebuffer {
+	fb := &Framebuffer{
+		Pixels:  make([]byte, mode.Width*mode.Height),
+		Palette: pal,
+		Width:   mode.Width,
+		Height:  mode.Height,
+		Stride:  mode.Width,
+		Mode:    mode,
+		clip:    image.Rect(0, 0, mode.Width, mode.Height),
+	}
+	if mode.IsText() {
+		fb.Text = NewTextScreen(mode.TextCols, mode.TextRows)
+		fb.Text.Clear(0) // transparent until an effect writes text
+	}
+	return fb
+}
+
+// Bounds returns the framebuffer's extent in local coordinates.
+func (fb *Framebuffer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, fb.Width, fb.Height)
 }
 
-// Clear fills the entire buffer with a single color index.
+// Clip returns the current clip rectangle. All drawing primitives except
+// the unchecked SetPixel/GetPixel stay inside it.
+func (fb *Framebuffer) Clip() image.Rectangle {
+	return fb.clip
+}
+
+// PushClip saves the current clip and narrows it to r (intersected with the
+// current clip, so nested regions never grow).
+func (fb *Framebuffer) PushClip(r image.Rectangle) {
+	fb.clips = append(fb.clips, fb.clip)
+	fb.clip = fb.clip.Intersect(r)
+}
+
+// PopClip restores the clip saved by the matching PushClip.
+func (fb *Framebuffer) PopClip() {
+	if n := len(fb.clips); n > 0 {
+		fb.clip = fb.clips[n-1]
+		fb.clips = fb.clips[:n-1]
+	}
+}
+
+// View returns a framebuffer for the region r of fb, with (0, 0) at r's
+// top-left corner. It shares fb's pixel memory, so an effect can render into
+// a viewport (split-screen, picture-in-picture) without copying. The view
+// starts clipped to the part of r inside fb's current clip.
+//
+// VGA has a single DAC, so SetPalette/SetPaletteColor on a view update the
+// palette of every framebuffer it was taken from, up to the root. Other
+// views keep the copy they were made with.
+func (fb *Framebuffer) View(r image.Rectangle) *Framebuffer {
+	r = r.Intersect(fb.Bounds())
+	v := &Framebuffer{
+		Palette: fb.Palette,
+		Width:   r.Dx(),
+		Height:  r.Dy(),
+		Stride:  fb.Stride,
+		Mode:    fb.Mode,
+		clip:    fb.clip.Intersect(r).Sub(r.Min),
+		parent:  fb,
+	}
+	if !r.Empty() {
+		v.Pixels = fb.Pixels[r.Min.Y*fb.Stride+r.Min.X : (r.Max.Y-1)*fb.Stride+r.Max.X]
+	}
+	return v
+}
+
+// Clear fills the clip rectangle (normally the whole buffer) with a single color index.
 func (fb *Framebuffer) Clear(colorIndex byte) {
-	for i := range fb.Pixels {
-		fb.Pixels[i] = colorIndex
+	if fb.clip == fb.Bounds() && fb.Stride == fb.Width && len(fb.Pixels) == fb.Width*fb.Height {
+		for i := range fb.Pixels {
+			fb.Pixels[i] = colorIndex
+		}
+		return
+	}
+	for y := fb.clip.Min.Y; y < fb.clip.Max.Y; y++ {
+		row := fb.Pixels[y*fb.Stride+fb.clip.Min.X : y*fb.Stride+fb.clip.Max.X]
+		for i := range row {
+			row[i] = colorIndex
+		}
 	}
 }
 
-// SetPixel writes a palette index at (x, y). No bounds checking for speed.
+// SetPixel writes a palette index at (x, y). No bounds or clip checking for speed.
 func (fb *Framebuffer) SetPixel(x, y int, colorIndex byte) {
-	fb.Pixels[y*Width+x] = colorIndex
+	fb.Pixels[y*fb.Stride+x] = colorIndex
 }
 
-// SetPixelSafe writes a palette index at (x, y) with bounds checking.
+// SetPixelSafe writes a palette index at (x, y) if it lies inside the clip rectangle.
 func (fb *Framebuffer) SetPixelSafe(x, y int, colorIndex byte) {
-	if x >= 0 && x < Width && y >= 0 && y < Height {
-		fb.Pixels[y*Width+x] = colorIndex
+	if x >= fb.clip.Min.X && x < fb.clip.Max.X && y >= fb.clip.Min.Y && y < fb.clip.Max.Y {
+		fb.Pixels[y*fb.Stride+x] = colorIndex
 	}
 }
 
 // GetPixel reads the palette index at (x, y). No bounds checking.
 func (fb *Framebuffer) GetPixel(x, y int) byte {
-	return fb.Pixels[y*Width+x]
+	return fb.Pixels[y*fb.Stride+x]
 }
 
-// HLine draws a horizontal line from (x0, y) to (x1, y).
-func (fb *Framebuffer) HLine(x0, x1, y int, colorIndex byte) {
-	if y < 0 || y >= Height {
-		return
+// clipSpan clips the inclusive horizontal span [x0, x1] on row y to the
+// clip rectangle and reports whether anything is left to draw.
+func (fb *Framebuffer) clipSpan(x0, x1, y int) (int, int, bool) {
+	if y < fb.clip.Min.Y || y >= fb.clip.Max.Y {
+		return 0, 0, false
 	}
 	if x0 > x1 {
 		x0, x1 = x1, x0
 	}
-	if x0 < 0 {
-		x0 = 0
+	if x0 < fb.clip.Min.X {
+		x0 = fb.clip.Min.X
 	}
-	if x1 >= Width {
-		x1 = Width - 1
+	if x1 >= fb.clip.Max.X {
+		x1 = fb.clip.Max.X - 1
 	}
-	offset := y * Width
+	return x0, x1, x0 <= x1
+}
+
+// HLine draws a horizontal line from (x0, y) to (x1, y).
+func (fb *Framebuffer) HLine(x0, x1, y int, colorIndex byte) {
+	x0, x1, ok := fb.clipSpan(x0, x1, y)
+	if !ok {
+		return
+	}
+	offset := y * fb.Stride
 	for x := x0; x <= x1; x++ {
 		fb.Pixels[offset+x] = colorIndex
 	}
@@ -70,20 +186,20 @@ func (fb *Framebuffer) HLine(x0, x1, y int, colorIndex byte) {
 
 // VLine draws a vertical line from (x, y0) to (x, y1).
 func (fb *Framebuffer) VLine(x, y0, y1 int, colorIndex byte) {
-	if x < 0 || x >= Width {
+	if x < fb.clip.Min.X || x >= fb.clip.Max.X {
 		return
 	}
 	if y0 > y1 {
 		y0, y1 = y1, y0
 	}
-	if y0 < 0 {
-		y0 = 0
+	if y0 < fb.clip.Min.Y {
+		y0 = fb.clip.Min.Y
 	}
-	if y1 >= Height {
-		y1 = Height - 1
+	if y1 >= fb.clip.Max.Y {
+		y1 = fb.clip.Max.Y - 1
 	}
 	for y := y0; y <= y1; y++ {
-		fb.Pixels[y*Width+x] = colorIndex
+		fb.Pixels[y*fb.Stride+x] = colorIndex
 	}
 }
 
@@ -94,33 +210,48 @@ func (fb *Framebuffer) FillRect(x0, y0, w, h int, colorIndex byte) {
 	}
 }
 
-// RGBA converts the indexed framebuffer to RGBA bytes using the current palette.
-// Returns a slice suitable for ebiten.Image.WritePixels().
-func (fb *Framebuffer) RGBA() []byte {
-	for i, idx := range fb.Pixels {
-		c := fb.Palette[idx]
-		off := i * 4
-		fb.rgba[off] = c.R
-		fb.rgba[off+1] = c.G
-		fb.rgba[off+2] = c.B
-		fb.rgba[off+3] = c.A
-	}
-	return fb.rgba[:]
-}
-
-// SetPalette replaces the current palette.
+// SetPalette replaces the current palette, and that of every framebuffer a
+// view was taken from.
 func (fb *Framebuffer) SetPalette(pal Palette) {
-	fb.Palette = pal
+	for f := fb; f != nil; f = f.parent {
+		f.Palette = pal
+	}
 }
 
-// SetPaletteColor sets a single palette entry.
+// SetPaletteColor sets a single palette entry, like SetPalette.
 func (fb *Framebuffer) SetPaletteColor(index byte, c color.RGBA) {
-	fb.Palette[index] = c
+	for f := fb; f != nil; f = f.parent {
+		f.Palette[index] = c
+	}
 }
 
-// CopyFrom copies another framebuffer's pixels (not palette) into this one.
+// CopyFrom copies another framebuffer's pixels (not palette) into this one,
+// row by row over the area both buffers cover.
 func (fb *Framebuffer) CopyFrom(src *Framebuffer) {
-	copy(fb.Pixels[:], src.Pixels[:])
+	if fb.Stride == src.Stride && fb.Width == src.Width && fb.Height == src.Height && fb.Stride == fb.Width {
+		copy(fb.Pixels, src.Pixels)
+		return
+	}
+	w := min(fb.Width, src.Width)
+	h := min(fb.Height, src.Height)
+	for y := 0; y < h; y++ {
+		copy(fb.Pixels[y*fb.Stride:y*fb.Stride+w], src.Pixels[y*src.Stride:y*src.Stride+w])
+	}
+}
+
+// CopyFromKeyed copies src over fb like CopyFrom, skipping pixels equal to
+// key, so src is layered on top with that colour transparent.
+func (fb *Framebuffer) CopyFromKeyed(src *Framebuffer, key byte) {
+	w := min(fb.Width, src.Width)
+	h := min(fb.Height, src.Height)
+	for y := 0; y < h; y++ {
+		dst := fb.Pixels[y*fb.Stride : y*fb.Stride+w]
+		for x, px := range src.Pixels[y*src.Stride : y*src.Stride+w] {
+			if px != key {
+				dst[x] = px
+			}
+		}
+	}
 }
 
 // FadeToBlack gradually darkens the palette over duration.
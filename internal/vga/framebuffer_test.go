@@ -0,0 +1,131 @@
+package vga
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPushClip(t *testing.T) {
+	tests := []struct {
+		name string
+		push []image.Rectangle
+		want image.Rectangle
+	}{
+		{"inside", []image.Rectangle{image.Rect(10, 10, 20, 20)}, image.Rect(10, 10, 20, 20)},
+		{"past the edges", []image.Rectangle{image.Rect(-5, -5, 400, 300)}, image.Rect(0, 0, 320, 200)},
+		{"off screen", []image.Rectangle{image.Rect(-50, -50, -10, -10)}, image.Rectangle{}},
+		{"nested narrows", []image.Rectangle{image.Rect(0, 0, 100, 100), image.Rect(50, 50, 200, 200)}, image.Rect(50, 50, 100, 100)},
+		{"nested never grows", []image.Rectangle{image.Rect(10, 10, 20, 20), image.Rect(0, 0, 320, 200)}, image.Rect(10, 10, 20, 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			for _, r := range tt.push {
+				fb.PushClip(r)
+			}
+			if got := fb.Clip(); got != tt.want && !(got.Empty() && tt.want.Empty()) {
+				t.Errorf("clip = %v, want %v", got, tt.want)
+			}
+			fb.Clear(5)
+			if got, want := count(fb, 5), tt.want.Dx()*tt.want.Dy(); got != want {
+				t.Errorf("Clear filled %d pixels, want %d", got, want)
+			}
+			for range tt.push {
+				fb.PopClip()
+			}
+			if fb.Clip() != fb.Bounds() {
+				t.Errorf("clip after PopClip = %v", fb.Clip())
+			}
+		})
+	}
+}
+
+func TestClippedPrimitives(t *testing.T) {
+	clip := image.Rect(100, 50, 110, 60)
+	tests := []struct {
+		name string
+		draw func(fb *Framebuffer)
+		want int
+	}{
+		{"HLine", func(fb *Framebuffer) { fb.HLine(-1000, 1000, 55, 1) }, 10},
+		{"VLine", func(fb *Framebuffer) { fb.VLine(105, 1000, -1000, 1) }, 10},
+		{"FillRect", func(fb *Framebuffer) { fb.FillRect(0, 0, 320, 200, 1) }, 100},
+		{"SetPixelSafe outside", func(fb *Framebuffer) { fb.SetPixelSafe(99, 55, 1) }, 0},
+		{"Line", func(fb *Framebuffer) { fb.Line(0, 55, 319, 55, 1) }, 10},
+		{"sprite", func(fb *Framebuffer) {
+			s := NewSprite(16, 16)
+			for i := range s.Pixels {
+				s.Pixels[i] = 1
+			}
+			fb.DrawSprite(95, 45, s)
+		}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.PushClip(clip)
+			tt.draw(fb)
+			if got := count(fb, 1); got != tt.want {
+				t.Errorf("drew %d pixels, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	fb := NewFramebuffer(DefaultPalette())
+	v := fb.View(image.Rect(300, 190, 400, 300)) // hangs off the corner
+	if v.Width != 20 || v.Height != 10 {
+		t.Fatalf("view is %dx%d, want 20x10", v.Width, v.Height)
+	}
+	v.Clear(4)
+	if got := count(fb, 4); got != 200 {
+		t.Errorf("view cleared %d parent pixels, want 200", got)
+	}
+	if fb.Pixels[190*fb.Stride+300] != 4 || fb.Pixels[189*fb.Stride+300] != 0 {
+		t.Error("view origin not at (300, 190)")
+	}
+
+	// A nested view inherits the parent's clip.
+	fb.PushClip(image.Rect(0, 0, 50, 50))
+	outer := fb.View(image.Rect(40, 40, 80, 80))
+	inner := outer.View(image.Rect(5, 5, 30, 30))
+	if want := image.Rect(0, 0, 5, 5); inner.Clip() != want {
+		t.Errorf("nested view clip = %v, want %v", inner.Clip(), want)
+	}
+	inner.FillRect(0, 0, 100, 100, 6)
+	if got := count(fb, 6); got != 25 {
+		t.Errorf("nested view filled %d pixels, want 25", got)
+	}
+	if fb.Pixels[45*fb.Stride+45] != 6 {
+		t.Error("nested view origin not at (45, 45)")
+	}
+
+	if empty := fb.View(image.Rect(500, 500, 600, 600)); empty.Width != 0 || empty.Pixels != nil {
+		t.Error("view outside the framebuffer is not empty")
+	}
+}
+
+// Palette writes on a nested view reach every framebuffer above it.
+func TestViewPalette(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	fb := NewFramebuffer(DefaultPalette())
+	outer := fb.View(image.Rect(10, 10, 100, 100))
+	inner := outer.View(image.Rect(10, 10, 50, 50))
+
+	inner.SetPaletteColor(3, red)
+	for name, f := range map[string]*Framebuffer{"root": fb, "outer": outer, "inner": inner} {
+		if f.Palette[3] != red {
+			t.Errorf("%s: SetPaletteColor not applied", name)
+		}
+	}
+
+	grey := greyRamp()
+	inner.SetPalette(grey)
+	for name, f := range map[string]*Framebuffer{"root": fb, "outer": outer, "inner": inner} {
+		if f.Palette != grey {
+			t.Errorf("%s: SetPalette not applied", name)
+		}
+	}
+}
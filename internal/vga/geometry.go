@@ -0,0 +1,356 @@
+package vga
+
+import "math"
+
+// Line draws a line from (x0, y0) to (x1, y1) using Bresenham's algorithm.
+// The line is clipped to the clip rectangle before rasterising, so the inner
+// loop writes pixels without bounds checks.
+func (fb *Framebuffer) Line(x0, y0, x1, y1 int, colorIndex byte) {
+	fx0, fy0, fx1, fy1, ok := clipLine(float64(x0), float64(y0), float64(x1), float64(y1),
+		float64(fb.clip.Min.X), float64(fb.clip.Min.Y), float64(fb.clip.Max.X-1), float64(fb.clip.Max.Y-1))
+	if !ok {
+		return
+	}
+	x0, y0 = int(math.Round(fx0)), int(math.Round(fy0))
+	x1, y1 = int(math.Round(fx1)), int(math.Round(fy1))
+
+	dx := x1 - x0
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := y1 - y0
+	if dy > 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		fb.Pixels[y0*fb.Stride+x0] = colorIndex
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// clipLine clips a segment to the inclusive box [minX,maxX]x[minY,maxY]
+// using Liang-Barsky. It reports false if the segment lies entirely outside.
+func clipLine(x0, y0, x1, y1, minX, minY, maxX, maxY float64) (float64, float64, float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx := x1 - x0
+	dy := y1 - y0
+	edges := [4][2]float64{
+		{-dx, x0 - minX},
+		{dx, maxX - x0},
+		{-dy, y0 - minY},
+		{dy, maxY - y0},
+	}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return 0, 0, 0, 0, false
+			}
+			if r > t0 {
+				t0 = r
+			}
+		} else {
+			if r < t0 {
+				return 0, 0, 0, 0, false
+			}
+			if r < t1 {
+				t1 = r
+			}
+		}
+	}
+	return x0 + t0*dx, y0 + t0*dy, x0 + t1*dx, y0 + t1*dy, true
+}
+
+// Circle draws a circle outline of radius r centred on (cx, cy).
+func (fb *Framebuffer) Circle(cx, cy, r int, colorIndex byte) {
+	fb.Ellipse(cx, cy, r, r, colorIndex)
+}
+
+// FillCircle draws a filled circle of radius r centred on (cx, cy).
+func (fb *Framebuffer) FillCircle(cx, cy, r int, colorIndex byte) {
+	fb.FillEllipse(cx, cy, r, r, colorIndex)
+}
+
+// Ellipse draws an axis-aligned ellipse outline with radii rx and ry.
+func (fb *Framebuffer) Ellipse(cx, cy, rx, ry int, colorIndex byte) {
+	midpointEllipse(rx, ry, func(x, y int) {
+		fb.SetPixelSafe(cx+x, cy+y, colorIndex)
+		fb.SetPixelSafe(cx-x, cy+y, colorIndex)
+		fb.SetPixelSafe(cx+x, cy-y, colorIndex)
+		fb.SetPixelSafe(cx-x, cy-y, colorIndex)
+	})
+}
+
+// FillEllipse draws a filled axis-aligned ellipse with radii rx and ry.
+func (fb *Framebuffer) FillEllipse(cx, cy, rx, ry int, colorIndex byte) {
+	midpointEllipse(rx, ry, func(x, y int) {
+		fb.HLine(cx-x, cx+x, cy+y, colorIndex)
+		fb.HLine(cx-x, cx+x, cy-y, colorIndex)
+	})
+}
+
+// midpointEllipse walks one quadrant of an ellipse with the midpoint
+// algorithm, calling plot for each point; callers mirror it.
+func midpointEllipse(rx, ry int, plot func(x, y int)) {
+	if rx < 0 || ry < 0 {
+		return
+	}
+	if rx == 0 || ry == 0 {
+		for x := 0; x <= rx; x++ {
+			plot(x, 0)
+		}
+		for y := 0; y <= ry; y++ {
+			plot(0, y)
+		}
+		return
+	}
+
+	rx2 := int64(rx) * int64(rx)
+	ry2 := int64(ry) * int64(ry)
+	x, y := int64(0), int64(ry)
+	px, py := int64(0), 2*rx2*y
+
+	// Region 1: slope shallower than -1.
+	p := ry2 - rx2*int64(ry) + rx2/4
+	for px < py {
+		plot(int(x), int(y))
+		x++
+		px += 2 * ry2
+		if p < 0 {
+			p += ry2 + px
+		} else {
+			y--
+			py -= 2 * rx2
+			p += ry2 + px - py
+		}
+	}
+
+	// Region 2: slope steeper than -1.
+	p = ry2*(2*x+1)*(2*x+1)/4 + rx2*(y-1)*(y-1) - rx2*ry2
+	for y >= 0 {
+		plot(int(x), int(y))
+		y--
+		py -= 2 * rx2
+		if p > 0 {
+			p += rx2 - py
+		} else {
+			x++
+			px += 2 * ry2
+			p += rx2 - py + px
+		}
+	}
+}
+
+// Vertex is a polygon corner in screen space with the attributes the
+// rasteriser can interpolate.
+type Vertex struct {
+	X, Y  float64 // screen position in pixels
+	Z     float64 // view depth for perspective-correct mapping (<= 0 means 1)
+	Shade float64 // palette index for Gouraud shading
+	U, V  float64 // texture coordinates in texels (wrap around the texture)
+}
+
+type polyMode int
+
+const (
+	polyFlat polyMode = iota
+	polyGouraud
+	polyAffine
+	polyPerspective
+)
+
+// FillPolygon fills a polygon with a single colour. Any simple polygon is
+// supported; spans are filled with the even-odd rule.
+func (fb *Framebuffer) FillPolygon(v []Vertex, colorIndex byte) {
+	fb.rasterPolygon(v, polyFlat, colorIndex, nil)
+}
+
+// FillPolygonGouraud fills a polygon interpolating each vertex's Shade
+// (a palette index) across the surface, for smooth shading into a ramp.
+func (fb *Framebuffer) FillPolygonGouraud(v []Vertex) {
+	fb.rasterPolygon(v, polyGouraud, 0, nil)
+}
+
+// FillPolygonTextured fills a polygon with a texture sampled at each vertex's
+// U/V. With perspective set, U/V are interpolated as U/Z, V/Z and 1/Z for
+// perspective-correct mapping; otherwise mapping is affine and Z is ignored.
+func (fb *Framebuffer) FillPolygonTextured(v []Vertex, tex *Sprite, perspective bool) {
+	if tex == nil || tex.Width <= 0 || tex.Height <= 0 {
+		return
+	}
+	mode := polyAffine
+	if perspective {
+		mode = polyPerspective
+	}
+	fb.rasterPolygon(v, mode, 0, tex)
+}
+
+// edgeHit is where a polygon edge crosses the current scanline, carrying
+// the interpolated attributes (shade, u, v, w) at that point.
+type edgeHit struct {
+	x float64
+	a [4]float64
+}
+
+func (fb *Framebuffer) rasterPolygon(v []Vertex, mode polyMode, colorIndex byte, tex *Sprite) {
+	n := len(v)
+	if n < 3 {
+		return
+	}
+
+	// Per-vertex attributes in screen-linear form.
+	var attrBuf [16][4]float64
+	attrs := attrBuf[:0]
+	minY, maxY := v[0].Y, v[0].Y
+	for i := range v {
+		var a [4]float64
+		a[0] = v[i].Shade
+		switch mode {
+		case polyAffine:
+			a[1], a[2], a[3] = v[i].U, v[i].V, 1
+		case polyPerspective:
+			w := 1.0
+			if v[i].Z > 0 {
+				w = 1 / v[i].Z
+			}
+			a[1], a[2], a[3] = v[i].U*w, v[i].V*w, w
+		}
+		attrs = append(attrs, a)
+		minY = math.Min(minY, v[i].Y)
+		maxY = math.Max(maxY, v[i].Y)
+	}
+
+	// Sample at pixel centres.
+	yStart := int(math.Ceil(minY - 0.5))
+	yEnd := int(math.Ceil(maxY-0.5)) - 1
+	if yStart < fb.clip.Min.Y {
+		yStart = fb.clip.Min.Y
+	}
+	if yEnd >= fb.clip.Max.Y {
+		yEnd = fb.clip.Max.Y - 1
+	}
+
+	var hitBuf [16]edgeHit
+	for y := yStart; y <= yEnd; y++ {
+		yc := float64(y) + 0.5
+		hits := hitBuf[:0]
+		for i := 0; i < n; i++ {
+			j := i + 1
+			if j == n {
+				j = 0
+			}
+			a, b := &v[i], &v[j]
+			if a.Y == b.Y {
+				continue
+			}
+			if !((yc >= a.Y && yc < b.Y) || (yc >= b.Y && yc < a.Y)) {
+				continue
+			}
+			t := (yc - a.Y) / (b.Y - a.Y)
+			h := edgeHit{x: a.X + t*(b.X-a.X)}
+			for k := range h.a {
+				h.a[k] = attrs[i][k] + t*(attrs[j][k]-attrs[i][k])
+			}
+			// Insertion sort by x; polygons rarely have more than a few crossings.
+			hits = append(hits, h)
+			for k := len(hits) - 1; k > 0 && hits[k].x < hits[k-1].x; k-- {
+				hits[k], hits[k-1] = hits[k-1], hits[k]
+			}
+		}
+		for k := 0; k+1 < len(hits); k += 2 {
+			fb.rasterSpan(y, &hits[k], &hits[k+1], mode, colorIndex, tex)
+		}
+	}
+}
+
+func (fb *Framebuffer) rasterSpan(y int, l, r *edgeHit, mode polyMode, colorIndex byte, tex *Sprite) {
+	xs := int(math.Ceil(l.x - 0.5))
+	xe := int(math.Ceil(r.x-0.5)) - 1
+	dx := r.x - l.x
+	if xs > xe || dx <= 0 {
+		return
+	}
+	if xs < fb.clip.Min.X {
+		xs = fb.clip.Min.X
+	}
+	if xe >= fb.clip.Max.X {
+		xe = fb.clip.Max.X - 1
+	}
+	if xs > xe {
+		return
+	}
+
+	// Attribute values at the first pixel centre, and their per-pixel step.
+	var a, da [4]float64
+	t := (float64(xs) + 0.5 - l.x) / dx
+	for k := range a {
+		da[k] = (r.a[k] - l.a[k]) / dx
+		a[k] = l.a[k] + t*(r.a[k]-l.a[k])
+	}
+
+	row := fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width]
+	switch mode {
+	case polyFlat:
+		for x := xs; x <= xe; x++ {
+			row[x] = colorIndex
+		}
+	case polyGouraud:
+		for x := xs; x <= xe; x++ {
+			s := a[0]
+			if s < 0 {
+				s = 0
+			} else if s > 255 {
+				s = 255
+			}
+			row[x] = byte(s)
+			a[0] += da[0]
+		}
+	case polyAffine, polyPerspective:
+		for x := xs; x <= xe; x++ {
+			u, v := a[1], a[2]
+			if mode == polyPerspective {
+				u /= a[3]
+				v /= a[3]
+			}
+			row[x] = tex.Pixels[wrap(int(math.Floor(v)), tex.Height)*tex.Width+wrap(int(math.Floor(u)), tex.Width)]
+			a[1] += da[1]
+			a[2] += da[2]
+			a[3] += da[3]
+		}
+	}
+}
+
+// wrap returns i modulo n in the range [0, n).
+func wrap(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
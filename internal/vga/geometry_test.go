@@ -0,0 +1,114 @@
+package vga
+
+import "testing"
+
+// count returns how many pixels of fb hold c.
+func count(fb *Framebuffer, c byte) int {
+	n := 0
+	for y := 0; y < fb.Height; y++ {
+		for _, p := range fb.Pixels[y*fb.Stride : y*fb.Stride+fb.Width] {
+			if p == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func quad(x0, y0, x1, y1 float64) []Vertex {
+	return []Vertex{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}
+}
+
+func TestFillPolygonCoverage(t *testing.T) {
+	tests := []struct {
+		name string
+		v    []Vertex
+		want int
+	}{
+		{"square", quad(10, 10, 20, 20), 100},
+		{"half-pixel offset", quad(10.5, 10.5, 20.5, 20.5), 100},
+		{"thinner than a pixel centre", quad(10, 10, 10.4, 20), 0},
+		{"degenerate", []Vertex{{X: 1, Y: 1}, {X: 5, Y: 1}}, 0},
+		{"past every edge", quad(-50, -50, 1000, 1000), 320 * 200},
+		{"off screen", quad(-50, -50, -10, -10), 0},
+		// A bow tie: the even-odd rule fills both lobes, 2 x 10 x 5.
+		{"self-intersecting", []Vertex{{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 10}}, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.FillPolygon(tt.v, 7)
+			if got := count(fb, 7); got != tt.want {
+				t.Errorf("filled %d pixels, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Triangles sharing an edge must cover every pixel exactly once: no gaps,
+// no double-drawn seam.
+func TestFillPolygonSharedEdge(t *testing.T) {
+	a := []Vertex{{X: 3, Y: 2}, {X: 47.3, Y: 9.1}, {X: 12.6, Y: 41.8}}
+	b := []Vertex{{X: 47.3, Y: 9.1}, {X: 51.2, Y: 44.4}, {X: 12.6, Y: 41.8}}
+	fa, fb := NewFramebuffer(DefaultPalette()), NewFramebuffer(DefaultPalette())
+	fa.FillPolygon(a, 1)
+	fb.FillPolygon(b, 1)
+	for i := range fa.Pixels {
+		if fa.Pixels[i] == 1 && fb.Pixels[i] == 1 {
+			t.Fatalf("pixel (%d, %d) drawn by both triangles", i%fa.Stride, i/fa.Stride)
+		}
+	}
+	both := NewFramebuffer(DefaultPalette())
+	both.FillPolygon([]Vertex{a[0], a[1], b[1], a[2]}, 1)
+	if got, want := count(fa, 1)+count(fb, 1), count(both, 1); got != want {
+		t.Errorf("triangles cover %d pixels, the quad %d", got, want)
+	}
+}
+
+func TestFillPolygonGouraud(t *testing.T) {
+	fb := NewFramebuffer(DefaultPalette())
+	fb.FillPolygonGouraud([]Vertex{
+		{X: 0, Y: 0, Shade: 0}, {X: 100, Y: 0, Shade: 100},
+		{X: 100, Y: 10, Shade: 100}, {X: 0, Y: 10, Shade: 0},
+	})
+	for _, x := range []int{0, 25, 50, 99} {
+		// Sampled at the pixel centre.
+		if got, want := fb.Pixels[5*fb.Stride+x], byte(x); got != want {
+			t.Errorf("shade at x=%d is %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestLineClipped(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+		want           int
+	}{
+		{"inside", 10, 10, 19, 10, 10},
+		{"across the screen", -100, 50, 500, 50, 320},
+		{"down the screen", 5, -10, 5, 300, 200},
+		{"outside", -10, -10, -1, -100, 0},
+		{"single point", 3, 3, 3, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.Line(tt.x0, tt.y0, tt.x1, tt.y1, 9)
+			if got := count(fb, 9); got != tt.want {
+				t.Errorf("drew %d pixels, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFillEllipseClipped(t *testing.T) {
+	fb := NewFramebuffer(DefaultPalette())
+	fb.FillCircle(0, 0, 10, 3) // three quarters off screen
+	if fb.Pixels[0] != 3 || fb.Pixels[9] != 3 || fb.Pixels[9*fb.Stride] != 3 {
+		t.Error("visible quarter not filled")
+	}
+	if fb.Pixels[10*fb.Stride+10] == 3 {
+		t.Error("filled outside the radius")
+	}
+}
@@ -0,0 +1,82 @@
+package vga
+
+import (
+	"image/color"
+	"strings"
+)
+
+// Mode describes a VGA display mode the framebuffer can emulate.
+type Mode struct {
+	Name   string
+	Width  int // visible pixels per line
+	Height int // visible lines
+	// Colors is 256 for chained and Mode X modes and 16 for planar and
+	// text modes. In 16-colour modes effects still draw with their 256-entry
+	// palettes, and scan-out shows each entry as the nearest of the 16
+	// standard colours.
+	Colors int
+
+	// Text modes: character grid rendered with the 8x8 CP437 font.
+	// Zero for graphics modes.
+	TextCols int
+	TextRows int
+}
+
+// Standard modes. Mode X variants are unchained 256-colour modes; here they
+// are stored linearly like 13h, so effects see them simply as larger screens.
+var (
+	Mode13h     = Mode{Name: "13h", Width: 320, Height: 200, Colors: 256}
+	ModeX       = Mode{Name: "x", Width: 320, Height: 240, Colors: 256}
+	ModeX360    = Mode{Name: "x360", Width: 360, Height: 240, Colors: 256}
+	ModeX400    = Mode{Name: "x400", Width: 320, Height: 400, Colors: 256}
+	Mode12h     = Mode{Name: "12h", Width: 640, Height: 480, Colors: 16}
+	ModeText80  = Mode{Name: "text80x50", Width: 640, Height: 400, Colors: 16, TextCols: 80, TextRows: 50}
+	DefaultMode = Mode13h
+)
+
+// Modes lists every mode selectable by name.
+func Modes() []Mode {
+	return []Mode{Mode13h, ModeX, ModeX360, ModeX400, Mode12h, ModeText80}
+}
+
+// ModeByName looks up a mode by its Name (case-insensitive).
+func ModeByName(name string) (Mode, bool) {
+	for _, m := range Modes() {
+		if strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+	}
+	return Mode{}, false
+}
+
+// IsText reports whether the mode is a character-cell text mode.
+func (m Mode) IsText() bool {
+	return m.TextCols > 0 && m.TextRows > 0
+}
+
+// standard16 is the 16-colour palette of the planar and text modes, the
+// same colours as entries 0-15 of DefaultPalette.
+var standard16 = func() (s [16]color.RGBA) {
+	p := DefaultPalette()
+	copy(s[:], p[:16])
+	return s
+}()
+
+// nearestStandard16 returns the index of the standard16 colour closest to c.
+func nearestStandard16(c color.RGBA) byte {
+	best, bestDist := 0, 1<<30
+	for i, s := range standard16 {
+		dr := int(s.R) - int(c.R)
+		dg := int(s.G) - int(c.G)
+		db := int(s.B) - int(c.B)
+		if dist := dr*dr + dg*dg + db*db; dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return byte(best)
+}
+
+// TextColor returns the text attribute colour (0-15) closest to c.
+func TextColor(c color.RGBA) byte {
+	return nearestStandard16(c)
+}
@@ -81,6 +81,16 @@ func FirePalette() Palette {
 	return p
 }
 
+// LerpColor mixes a and b, t from 0 (a) to 1 (b). The result is opaque.
+func LerpColor(a, b color.RGBA, t float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(a.R) + t*(float64(b.R)-float64(a.R))),
+		G: uint8(float64(a.G) + t*(float64(b.G)-float64(a.G))),
+		B: uint8(float64(a.B) + t*(float64(b.B)-float64(a.B))),
+		A: 255,
+	}
+}
+
 // GradientPalette creates a smooth gradient between two colors across all 256 entries.
 func GradientPalette(from, to color.RGBA) Palette {
 	var p Palette
@@ -124,3 +134,44 @@ func sin(x float64) float64 {
 	}
 	return (16*x*(3.14159265-x))/(49.348-(4*x*(3.14159265-x)))
 }
+
+// PaletteByName returns one of the built-in palettes: "default", "fire",
+// "plasma" or "grey".
+func PaletteByName(name string) (Palette, bool) {
+	switch name {
+	case "default":
+		return DefaultPalette(), true
+	case "fire":
+		return FirePalette(), true
+	case "plasma":
+		return PlasmaPalette(), true
+	case "grey", "gray":
+		return GradientPalette(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}), true
+	}
+	return Palette{}, false
+}
+
+// RampPalette spreads a multi-stop gradient evenly across all 256 entries.
+// With a single stop the whole palette is that colour.
+func RampPalette(stops ...color.RGBA) Palette {
+	var p Palette
+	if len(stops) == 0 {
+		return p
+	}
+	if len(stops) == 1 {
+		for i := range p {
+			p[i] = stops[0]
+		}
+		return p
+	}
+	segs := len(stops) - 1
+	for i := 0; i < 256; i++ {
+		pos := float64(i) / 255.0 * float64(segs)
+		s := int(pos)
+		if s >= segs {
+			s = segs - 1
+		}
+		p[i] = LerpColor(stops[s], stops[s+1], pos-float64(s))
+	}
+	return p
+}
@@ -0,0 +1,179 @@
+package vga
+
+import (
+	"encoding/binary"
+	"image/color"
+	"slices"
+)
+
+// RasterState is the display state the beam uses for the current scanline
+// during RGBA conversion. Changes persist for the rest of the frame, like
+// writes to the real DAC and pel-panning registers.
+type RasterState struct {
+	Line    int     // scanline about to be displayed
+	Palette Palette // DAC contents for this line onward
+	ScrollX int     // horizontal pixel offset for this line, added to PixelPan
+}
+
+// ScanlineFunc is a raster-interrupt style hook called before each scanline.
+type ScanlineFunc func(rs *RasterState)
+
+type copperKind uint8
+
+const (
+	copperColor copperKind = iota
+	copperScroll
+)
+
+// CopperOp is a single register write performed when the beam reaches Line.
+type CopperOp struct {
+	Line   int
+	kind   copperKind
+	Index  byte
+	Color  color.RGBA
+	Scroll int
+}
+
+// CopperList is an Amiga-style list of palette and scroll writes keyed by
+// scanline. Rebuild it each frame with Reset; the backing slice is reused.
+type CopperList struct {
+	ops    []CopperOp
+	sorted bool
+}
+
+// Reset empties the list, keeping its capacity.
+func (c *CopperList) Reset() {
+	c.ops = c.ops[:0]
+	c.sorted = true
+}
+
+// SetColor writes palette entry index when the beam reaches line.
+func (c *CopperList) SetColor(line int, index byte, col color.RGBA) {
+	c.add(CopperOp{Line: line, kind: copperColor, Index: index, Color: col})
+}
+
+// SetScroll sets the horizontal pixel offset from line onward.
+func (c *CopperList) SetScroll(line, dx int) {
+	c.add(CopperOp{Line: line, kind: copperScroll, Scroll: dx})
+}
+
+// Gradient rewrites palette entry index on every line from line0 to line1
+// (inclusive), blending from one colour to the other: a classic raster bar.
+func (c *CopperList) Gradient(line0, line1 int, index byte, from, to color.RGBA) {
+	if line1 < line0 {
+		line0, line1 = line1, line0
+	}
+	n := line1 - line0
+	for l := line0; l <= line1; l++ {
+		t := 0.0
+		if n > 0 {
+			t = float64(l-line0) / float64(n)
+		}
+		c.SetColor(l, index, LerpColor(from, to, t))
+	}
+}
+
+func (c *CopperList) add(op CopperOp) {
+	if n := len(c.ops); n > 0 && c.ops[n-1].Line > op.Line {
+		c.sorted = false
+	}
+	c.ops = append(c.ops, op)
+}
+
+// sortedOps returns the ops in line order; writes on the same line keep
+// the order they were added in.
+func (c *CopperList) sortedOps() []CopperOp {
+	if !c.sorted {
+		slices.SortStableFunc(c.ops, func(a, b CopperOp) int { return a.Line - b.Line })
+		c.sorted = true
+	}
+	return c.ops
+}
+
+func (op *CopperOp) apply(rs *RasterState) {
+	switch op.kind {
+	case copperColor:
+		rs.Palette[op.Index] = op.Color
+	case copperScroll:
+		rs.ScrollX = op.Scroll
+	}
+}
+
+// SetScanlineFunc registers a per-scanline hook applied during RGBA.
+func (fb *Framebuffer) SetScanlineFunc(fn ScanlineFunc) {
+	fb.scanline = fn
+}
+
+// SetCopper installs a copper list applied during RGBA.
+func (fb *Framebuffer) SetCopper(c *CopperList) {
+	fb.copper = c
+}
+
+// Copper returns the installed copper list, or nil.
+func (fb *Framebuffer) Copper() *CopperList {
+	return fb.copper
+}
+
+// ResetRaster removes the scanline hook and copper list. The sequencer calls
+// this every frame before drawing, so effects register raster hooks in Draw.
+func (fb *Framebuffer) ResetRaster() {
+	fb.scanline = nil
+	fb.copper = nil
+}
+
+// rgbaScan is the RGBA slow path used when raster hooks are installed or
+// the scroll registers are in use. It emulates scan-out line by line: each
+// line starts at StartAddr (or offset 0 below LineCompare), shifted by
+// PixelPan and the raster ScrollX, wrapping within the virtual row.
+func (fb *Framebuffer) rgbaScan() {
+	rs := RasterState{Palette: fb.Palette}
+	var ops []CopperOp
+	if fb.copper != nil {
+		ops = fb.copper.sortedOps()
+	}
+	_, vrows := fb.VirtualSize()
+	if fb.Width == 0 || vrows == 0 {
+		return
+	}
+	startX := wrap(fb.StartAddr, fb.Stride)
+	startY := fb.StartAddr / fb.Stride
+
+	// Working copy of the packed palette; mid-frame DAC writes repack only
+	// when the raster state's palette actually changes.
+	packed := fb.packed
+	cur := rs.Palette
+	colors := fb.packedColors
+
+	k := 0
+	for y := 0; y < fb.Height; y++ {
+		rs.Line = y
+		for k < len(ops) && ops[k].Line <= y {
+			ops[k].apply(&rs)
+			k++
+		}
+		if fb.scanline != nil {
+			fb.scanline(&rs)
+		}
+		if rs.Palette != cur {
+			cur = rs.Palette
+			packPalette(&packed, &cur, colors)
+		}
+
+		srcY := startY + y
+		sx := startX + fb.PixelPan
+		if fb.LineCompare > 0 && y >= fb.LineCompare {
+			srcY = y - fb.LineCompare
+			sx = 0
+		}
+		base := wrap(srcY, vrows) * fb.Stride
+		row := fb.Pixels[base:min(base+fb.Stride, len(fb.Pixels))]
+		sx = wrap(sx+rs.ScrollX, len(row))
+		out := fb.rgba[y*fb.Width*4 : (y+1)*fb.Width*4]
+		for x := 0; x < fb.Width; x++ {
+			binary.LittleEndian.PutUint32(out[x*4:], packed[row[sx]])
+			if sx++; sx == len(row) {
+				sx = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package vga
+
+import (
+	"image/color"
+	"testing"
+)
+
+// columns draws column x in index x, so shown() reads back the column.
+func columns() *Framebuffer {
+	fb := NewFramebuffer(indexPalette())
+	for x := 0; x < fb.Width; x++ {
+		fb.VLine(x, 0, fb.Height-1, byte(x))
+	}
+	return fb
+}
+
+func TestCopperList(t *testing.T) {
+	blue := color.RGBA{0, 0, 200, 255}
+	green := color.RGBA{0, 200, 0, 255}
+	fb := columns()
+	var c CopperList
+	c.Reset()
+	// Added out of line order; writes to the same line apply in order.
+	c.SetScroll(120, 3)
+	c.SetColor(40, 0, blue)
+	c.SetColor(80, 0, blue)
+	c.SetColor(80, 0, green)
+	c.SetScroll(130, 0)
+	fb.SetCopper(&c)
+	out := fb.RGBA()
+	at := func(x, y int) color.RGBA {
+		i := (y*fb.Width + x) * 4
+		return color.RGBA{out[i], out[i+1], out[i+2], out[i+3]}
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"before the first write", 0, 39, color.RGBA{0, 0, 0, 255}},
+		{"colour write", 0, 40, blue},
+		{"persists", 0, 79, blue},
+		{"last write on a line wins", 0, 80, green},
+		{"other entries untouched", 1, 80, color.RGBA{1, 0, 0, 255}},
+		{"unscrolled", 5, 119, color.RGBA{5, 0, 0, 255}},
+		{"scrolled", 5, 120, color.RGBA{8, 0, 0, 255}},
+		{"scroll wraps", 319, 120, color.RGBA{2, 0, 0, 255}},
+		{"scroll reset", 5, 130, color.RGBA{5, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		if got := at(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: (%d, %d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	fb.ResetRaster()
+	if fb.Copper() != nil {
+		t.Error("copper list still installed")
+	}
+	if got := shown(fb, 0, 80); got != 0 {
+		t.Errorf("after ResetRaster line 80 shows red %d, want 0", got)
+	}
+	if got := shown(fb, 5, 120); got != 5 {
+		t.Errorf("after ResetRaster line 120 shows column %d, want 5", got)
+	}
+}
+
+func TestScanlineFunc(t *testing.T) {
+	fb := columns()
+	var lines []int
+	fb.SetScanlineFunc(func(rs *RasterState) {
+		lines = append(lines, rs.Line)
+		switch rs.Line {
+		case 50:
+			rs.Palette[7] = color.RGBA{250, 0, 0, 255}
+			rs.ScrollX = -2
+		case 60:
+			rs.ScrollX = 0
+		}
+	})
+	tests := []struct {
+		name string
+		x, y int
+		want byte
+	}{
+		{"before the hook", 7, 49, 7},
+		{"palette write", 7, 60, 250},
+		{"scrolled", 9, 50, 250},    // column 7
+		{"scroll wraps", 0, 59, 62}, // column 318
+		{"scroll reset", 9, 60, 9},
+	}
+	for _, tt := range tests {
+		if got := shown(fb, tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: (%d, %d) shows %d, want %d", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+	// Once per line, top to bottom, for each RGBA.
+	if len(lines) != len(tests)*fb.Height || lines[0] != 0 || lines[fb.Height-1] != fb.Height-1 {
+		t.Errorf("hook called for %d lines over %d frames", len(lines), len(tests))
+	}
+	if fb.Palette[7] != (color.RGBA{7, 0, 0, 255}) {
+		t.Error("hook wrote to fb.Palette")
+	}
+
+	fb.ResetRaster()
+	lines = lines[:0]
+	if got := shown(fb, 7, 60); got != 7 || len(lines) != 0 {
+		t.Errorf("after ResetRaster: shows %d, hook called %d times", got, len(lines))
+	}
+}
+
+// The scanline hook runs after the copper list on each line, so it sees
+// and can override the copper's writes.
+func TestScanlineFuncAfterCopper(t *testing.T) {
+	fb := columns()
+	var c CopperList
+	c.Reset()
+	c.SetColor(10, 3, color.RGBA{100, 0, 0, 255})
+	fb.SetCopper(&c)
+	var seen byte
+	fb.SetScanlineFunc(func(rs *RasterState) {
+		if rs.Line == 10 {
+			seen = rs.Palette[3].R
+			rs.Palette[3].R++
+		}
+	})
+	if got := shown(fb, 3, 10); seen != 100 || got != 101 {
+		t.Errorf("hook saw %d, line shows %d, want 100 and 101", seen, got)
+	}
+}
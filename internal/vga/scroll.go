@@ -0,0 +1,100 @@
+package vga
+
+import (
+	"errors"
+	"image"
+)
+
+// Hardware scrolling and page flipping, emulating the CRTC start address,
+// the attribute controller's pixel panning and the line compare register.
+//
+// Pixels acts as video memory: SetVirtualSize makes it larger than the
+// visible Width x Height. Drawing on the framebuffer itself always targets
+// the top-left of video memory; use VRAM or Page for views elsewhere. RGBA
+// scans out starting at StartAddr, so moving StartAddr scrolls or flips
+// pages without copying any pixels.
+
+// ErrViewResize is returned when resizing video memory through a view,
+// whose memory belongs to the framebuffer it was taken from.
+var ErrViewResize = errors.New("vga: video memory can only be resized on the root framebuffer")
+
+// SetVirtualSize reallocates video memory as a w x h virtual screen (at least
+// the visible size) and resets the scroll registers. Contents are cleared.
+// Views share their parent's memory and cannot be resized.
+func (fb *Framebuffer) SetVirtualSize(w, h int) error {
+	if fb.parent != nil {
+		return ErrViewResize
+	}
+	w = max(w, fb.Width)
+	h = max(h, fb.Height)
+	fb.Pixels = make([]byte, w*h)
+	fb.Stride = w
+	fb.StartAddr = 0
+	fb.PixelPan = 0
+	fb.LineCompare = 0
+	return nil
+}
+
+// SetPages sizes video memory to hold n full screens stacked vertically.
+func (fb *Framebuffer) SetPages(n int) error {
+	return fb.SetVirtualSize(fb.Width, fb.Height*max(n, 1))
+}
+
+// ResetScroll puts video memory back to the visible size, if it was
+// enlarged, and zeroes the scroll registers, so the next effect starts
+// from a plain screen. The sequencer calls this on every effect change.
+func (fb *Framebuffer) ResetScroll() {
+	if fb.parent == nil && (fb.Stride != fb.Width || len(fb.Pixels) != fb.Width*fb.Height) {
+		fb.Pixels = make([]byte, fb.Width*fb.Height)
+		fb.Stride = fb.Width
+	}
+	fb.StartAddr = 0
+	fb.PixelPan = 0
+	fb.LineCompare = 0
+}
+
+// VirtualSize returns the size of video memory in pixels.
+func (fb *Framebuffer) VirtualSize() (w, h int) {
+	if fb.Stride == 0 {
+		return 0, 0
+	}
+	return fb.Stride, (len(fb.Pixels) + fb.Stride - 1) / fb.Stride
+}
+
+// VRAM returns a view covering all of video memory in virtual coordinates.
+func (fb *Framebuffer) VRAM() *Framebuffer {
+	w, h := fb.VirtualSize()
+	v := *fb
+	v.Width, v.Height = w, h
+	v.clip = v.Bounds()
+	v.clips = nil
+	v.rgba = nil
+	v.Text = nil
+	v.StartAddr, v.PixelPan, v.LineCompare = 0, 0, 0
+	v.parent = fb
+	return &v
+}
+
+// Page returns a screen-sized view of page n (see SetPages).
+func (fb *Framebuffer) Page(n int) *Framebuffer {
+	return fb.VRAM().View(fb.Bounds().Add(image.Pt(0, n*fb.Height)))
+}
+
+// ShowPage points scan-out at page n, i.e. a page flip.
+func (fb *Framebuffer) ShowPage(n int) {
+	fb.StartAddr = n * fb.Height * fb.Stride
+	fb.PixelPan = 0
+}
+
+// SetStart scrolls so that virtual pixel (x, y) appears at the top-left of
+// the screen. Like Mode X, the start address moves in 4-pixel steps and the
+// remainder goes into PixelPan.
+func (fb *Framebuffer) SetStart(x, y int) {
+	fb.StartAddr = y*fb.Stride + x&^3
+	fb.PixelPan = x & 3
+}
+
+// scrolled reports whether scan-out differs from reading rows at offset 0.
+func (fb *Framebuffer) scrolled() bool {
+	return fb.StartAddr != 0 || fb.PixelPan != 0 || fb.LineCompare > 0
+}
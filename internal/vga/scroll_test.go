@@ -0,0 +1,105 @@
+package vga
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// indexPalette maps entry i to red i, so RGBA output reads back as indices.
+func indexPalette() Palette {
+	var p Palette
+	for i := range p {
+		p[i] = color.RGBA{uint8(i), 0, 0, 255}
+	}
+	return p
+}
+
+// shown returns the index displayed at (x, y) after RGBA.
+func shown(fb *Framebuffer, x, y int) byte {
+	return fb.RGBA()[(y*fb.Width+x)*4]
+}
+
+func TestPageFlip(t *testing.T) {
+	fb := NewFramebuffer(indexPalette())
+	if err := fb.SetPages(3); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 3; n++ {
+		fb.Page(n).Clear(byte(10 + n))
+	}
+	for n := 0; n < 3; n++ {
+		fb.ShowPage(n)
+		for _, p := range []image.Point{{0, 0}, {319, 199}} {
+			if got := shown(fb, p.X, p.Y); got != byte(10+n) {
+				t.Errorf("page %d: %v shows %d", n, p, got)
+			}
+		}
+	}
+}
+
+func TestScanOut(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(fb *Framebuffer)
+		x, y  int
+		want  byte // virtual column, see below
+	}{
+		{"no scroll", func(fb *Framebuffer) {}, 5, 0, 5},
+		{"start address", func(fb *Framebuffer) { fb.SetStart(100, 0) }, 5, 0, 105},
+		{"pixel pan", func(fb *Framebuffer) { fb.SetStart(101, 0) }, 5, 0, 106},
+		{"wraps within the row", func(fb *Framebuffer) { fb.SetStart(600, 0) }, 50, 0, 10}, // 650 - 640
+		{"line compare above the split", func(fb *Framebuffer) { fb.SetStart(100, 0); fb.LineCompare = 150 }, 5, 149, 105},
+		{"line compare below the split", func(fb *Framebuffer) { fb.SetStart(100, 0); fb.LineCompare = 150 }, 5, 150, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(indexPalette())
+			if err := fb.SetVirtualSize(640, 200); err != nil {
+				t.Fatal(err)
+			}
+			// Each virtual pixel holds its column (mod 256).
+			vram := fb.VRAM()
+			for x := 0; x < 640; x++ {
+				vram.VLine(x, 0, 199, byte(x))
+			}
+			tt.setup(fb)
+			if got := shown(fb, tt.x, tt.y); got != tt.want {
+				t.Errorf("(%d, %d) shows %d, want %d", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetScroll(t *testing.T) {
+	fb := NewFramebuffer(indexPalette())
+	if err := fb.SetPages(2); err != nil {
+		t.Fatal(err)
+	}
+	fb.ShowPage(1)
+	fb.PixelPan, fb.LineCompare = 2, 100
+	fb.ResetScroll()
+	if w, h := fb.VirtualSize(); w != 320 || h != 200 {
+		t.Errorf("virtual size %dx%d after reset", w, h)
+	}
+	if fb.StartAddr != 0 || fb.PixelPan != 0 || fb.LineCompare != 0 || fb.scrolled() {
+		t.Error("scroll registers not reset")
+	}
+	fb.Clear(7)
+	if got := shown(fb, 319, 199); got != 7 {
+		t.Errorf("shows %d after reset, want 7", got)
+	}
+}
+
+func TestSetVirtualSizeOnView(t *testing.T) {
+	fb := NewFramebuffer(indexPalette())
+	v := fb.View(image.Rect(0, 0, 100, 100))
+	if err := v.SetVirtualSize(200, 200); !errors.Is(err, ErrViewResize) {
+		t.Errorf("SetVirtualSize on a view: err = %v", err)
+	}
+	v.Clear(3)
+	if fb.Pixels[0] != 3 {
+		t.Error("view detached from its parent's memory")
+	}
+}
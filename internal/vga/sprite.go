@@ -1,5 +1,10 @@
 package vga
 
+import (
+	"image"
+	"math"
+)
+
 type Sprite struct {
 	Width  int
 	Height int
@@ -37,13 +42,84 @@ func (s *Sprite) GetPixel(x, y int) byte {
 	return s.Pixels[y*s.Width+x]
 }
 
+// RemapTable maps each source palette index to the index actually drawn.
+type RemapTable [256]byte
+
+// IdentityRemap returns a table that leaves every index unchanged.
+func IdentityRemap() RemapTable {
+	var t RemapTable
+	for i := range t {
+		t[i] = byte(i)
+	}
+	return t
+}
+
+// OffsetRemap returns a table that shifts every index by offset (wrapping),
+// e.g. to draw the same sprite in a different 16- or 32-colour bank.
+func OffsetRemap(offset int) RemapTable {
+	var t RemapTable
+	for i := range t {
+		t[i] = byte(i + offset)
+	}
+	return t
+}
+
+// SpriteOptions controls how the DrawSprite* family renders a sprite.
+// The zero value draws the sprite unflipped with index 0 as transparent.
+type SpriteOptions struct {
+	FlipX  bool        // mirror horizontally
+	FlipY  bool        // mirror vertically
+	Key    byte        // colour key: pixels with this index are skipped
+	Opaque bool        // ignore Key and draw every pixel
+	Remap  *RemapTable // optional index remap applied to drawn pixels
+	Blend  *BlendTable // optional translucency table, applied after Remap
+}
+
+// plot writes a single source pixel honouring the colour key, remap and blend.
+func (o *SpriteOptions) plot(dst []byte, i int, px byte) {
+	if !o.Opaque && px == o.Key {
+		return
+	}
+	if o.Remap != nil {
+		px = o.Remap[px]
+	}
+	if o.Blend != nil {
+		px = o.Blend[px][dst[i]]
+	}
+	dst[i] = px
+}
+
+// clipBounds intersects the half-open rectangle [x0,x1)x[y0,y1) with the
+// clip rectangle and reports whether anything is left to draw.
+func (fb *Framebuffer) clipBounds(x0, y0, x1, y1 int) (int, int, int, int, bool) {
+	r := image.Rect(x0, y0, x1, y1).Intersect(fb.clip)
+	return r.Min.X, r.Min.Y, r.Max.X, r.Max.Y, !r.Empty()
+}
+
 func (fb *Framebuffer) DrawSprite(x, y int, s *Sprite) {
-	for fy := 0; fy < s.Height; fy++ {
-		for fx := 0; fx < s.Width; fx++ {
-			px := s.GetPixel(fx, fy)
-			if px != 0 {
-				fb.SetPixel(x+fx, y+fy, px)
+	fb.DrawSpriteEx(x, y, s, SpriteOptions{})
+}
+
+// DrawSpriteEx draws a sprite at (x, y) with flipping, colour key and remap.
+// The sprite is clipped once against the clip rectangle before any pixels are written.
+func (fb *Framebuffer) DrawSpriteEx(x, y int, s *Sprite, opts SpriteOptions) {
+	x0, y0, x1, y1, ok := fb.clipBounds(x, y, x+s.Width, y+s.Height)
+	if !ok {
+		return
+	}
+	for dy := y0; dy < y1; dy++ {
+		sy := dy - y
+		if opts.FlipY {
+			sy = s.Height - 1 - sy
+		}
+		src := s.Pixels[sy*s.Width : (sy+1)*s.Width]
+		off := dy * fb.Stride
+		for dx := x0; dx < x1; dx++ {
+			sx := dx - x
+			if opts.FlipX {
+				sx = s.Width - 1 - sx
 			}
+			opts.plot(fb.Pixels, off+dx, src[sx])
 		}
 	}
 }
@@ -52,16 +128,88 @@ func (fb *Framebuffer) DrawSpriteScaled(x, y int, s *Sprite, scale int) {
 	if scale <= 0 {
 		scale = 1
 	}
-	for fy := 0; fy < s.Height; fy++ {
-		for fx := 0; fx < s.Width; fx++ {
-			px := s.GetPixel(fx, fy)
-			if px != 0 {
-				for sy := 0; sy < scale; sy++ {
-					for sx := 0; sx < scale; sx++ {
-						fb.SetPixel(x+fx*scale+sx, y+fy*scale+sy, px)
-					}
+	fb.DrawSpriteZoom(x, y, s, float64(scale), float64(scale), SpriteOptions{})
+}
+
+// DrawSpriteZoom draws a sprite with its top-left corner at (x, y), scaled by
+// arbitrary fractional factors. Source pixels are stepped in 16.16 fixed point.
+func (fb *Framebuffer) DrawSpriteZoom(x, y int, s *Sprite, scaleX, scaleY float64, opts SpriteOptions) {
+	dw := int(float64(s.Width)*scaleX + 0.5)
+	dh := int(float64(s.Height)*scaleY + 0.5)
+	if dw <= 0 || dh <= 0 {
+		return
+	}
+	x0, y0, x1, y1, ok := fb.clipBounds(x, y, x+dw, y+dh)
+	if !ok {
+		return
+	}
+
+	stepU := (s.Width << 16) / dw
+	stepV := (s.Height << 16) / dh
+	for dy := y0; dy < y1; dy++ {
+		sy := ((dy - y) * stepV) >> 16
+		if opts.FlipY {
+			sy = s.Height - 1 - sy
+		}
+		src := s.Pixels[sy*s.Width : (sy+1)*s.Width]
+		off := dy * fb.Stride
+		u := (x0 - x) * stepU
+		for dx := x0; dx < x1; dx++ {
+			sx := u >> 16
+			if opts.FlipX {
+				sx = s.Width - 1 - sx
+			}
+			opts.plot(fb.Pixels, off+dx, src[sx])
+			u += stepU
+		}
+	}
+}
+
+// DrawSpriteRotozoom draws a sprite rotated by angle (radians) and scaled by
+// scale, with the sprite's centre placed at (cx, cy). Each destination pixel
+// is inverse-mapped into the sprite, so there are no holes at any angle.
+func (fb *Framebuffer) DrawSpriteRotozoom(cx, cy int, s *Sprite, angle, scale float64, opts SpriteOptions) {
+	if scale <= 0 {
+		return
+	}
+	// Bounding box of the rotated sprite is contained in its circumcircle.
+	r := int(math.Ceil(math.Hypot(float64(s.Width), float64(s.Height)) * scale / 2))
+	x0, y0, x1, y1, ok := fb.clipBounds(cx-r, cy-r, cx+r+1, cy+r+1)
+	if !ok {
+		return
+	}
+
+	// Inverse transform in 16.16 fixed point: destination step → source step.
+	sin, cos := math.Sincos(angle)
+	duCol := int(cos / scale * 65536)
+	dvCol := int(-sin / scale * 65536)
+	duRow := int(sin / scale * 65536)
+	dvRow := int(cos / scale * 65536)
+	halfW := s.Width << 15
+	halfH := s.Height << 15
+	w16 := s.Width << 16
+	h16 := s.Height << 16
+
+	for dy := y0; dy < y1; dy++ {
+		px := x0 - cx
+		py := dy - cy
+		u := px*duCol + py*duRow + halfW
+		v := px*dvCol + py*dvRow + halfH
+		off := dy * fb.Stride
+		for dx := x0; dx < x1; dx++ {
+			if u >= 0 && u < w16 && v >= 0 && v < h16 {
+				sx := u >> 16
+				sy := v >> 16
+				if opts.FlipX {
+					sx = s.Width - 1 - sx
+				}
+				if opts.FlipY {
+					sy = s.Height - 1 - sy
 				}
+				opts.plot(fb.Pixels, off+dx, s.Pixels[sy*s.Width+sx])
 			}
+			u += duCol
+			v += dvCol
 		}
 	}
 }
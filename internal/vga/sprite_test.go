@@ -0,0 +1,174 @@
+package vga
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"slices"
+	"testing"
+)
+
+// region returns the w x h block of fb at (x, y), row by row.
+func region(fb *Framebuffer, x, y, w, h int) []byte {
+	var out []byte
+	for row := y; row < y+h; row++ {
+		out = append(out, fb.Pixels[row*fb.Stride+x:row*fb.Stride+x+w]...)
+	}
+	return out
+}
+
+// drawn counts the pixels of fb that are not 0.
+func drawn(fb *Framebuffer) int {
+	return fb.Width*fb.Height - count(fb, 0)
+}
+
+// testSprite is 4x2: 1 2 3 4 over 5 6 7 8.
+func testSprite() *Sprite {
+	return NewSpriteFromData(4, 2, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+}
+
+func TestDrawSpriteEx(t *testing.T) {
+	remap := OffsetRemap(16)
+	tests := []struct {
+		name string
+		opts SpriteOptions
+		want []byte
+	}{
+		{"plain", SpriteOptions{}, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"flip x", SpriteOptions{FlipX: true}, []byte{4, 3, 2, 1, 8, 7, 6, 5}},
+		{"flip y", SpriteOptions{FlipY: true}, []byte{5, 6, 7, 8, 1, 2, 3, 4}},
+		{"flip both", SpriteOptions{FlipX: true, FlipY: true}, []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+		{"colour key", SpriteOptions{Key: 3}, []byte{1, 2, 0, 4, 5, 6, 7, 8}},
+		{"opaque ignores the key", SpriteOptions{Key: 3, Opaque: true}, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		{"remap", SpriteOptions{Remap: &remap}, []byte{17, 18, 19, 20, 21, 22, 23, 24}},
+		{"key before remap", SpriteOptions{Key: 3, Remap: &remap}, []byte{17, 18, 0, 20, 21, 22, 23, 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.DrawSpriteEx(10, 10, testSprite(), tt.opts)
+			if got := region(fb, 10, 10, 4, 2); !slices.Equal(got, tt.want) {
+				t.Errorf("drew %v, want %v", got, tt.want)
+			}
+			if got, want := drawn(fb), 8-bytes.Count(tt.want, []byte{0}); got != want {
+				t.Errorf("drew %d pixels, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestDrawSpriteClipped(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   int
+		clip   image.Rectangle
+		pixels map[image.Point]byte // every pixel expected to be drawn
+	}{
+		{"top left", -2, -1, image.Rect(0, 0, 320, 200), map[image.Point]byte{{0, 0}: 7, {1, 0}: 8}},
+		{"bottom right", 318, 199, image.Rect(0, 0, 320, 200), map[image.Point]byte{{318, 199}: 1, {319, 199}: 2}},
+		{"clip rectangle", 10, 10, image.Rect(11, 11, 13, 20), map[image.Point]byte{{11, 11}: 6, {12, 11}: 7}},
+		{"off screen", 320, 0, image.Rect(0, 0, 320, 200), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.PushClip(tt.clip)
+			fb.DrawSprite(tt.x, tt.y, testSprite())
+			for p, want := range tt.pixels {
+				if got := fb.Pixels[p.Y*fb.Stride+p.X]; got != want {
+					t.Errorf("%v = %d, want %d", p, got, want)
+				}
+			}
+			if got := drawn(fb); got != len(tt.pixels) {
+				t.Errorf("drew %d pixels, want %d", got, len(tt.pixels))
+			}
+		})
+	}
+}
+
+func TestDrawSpriteZoom(t *testing.T) {
+	s := NewSpriteFromData(2, 2, []byte{1, 2, 3, 4})
+	tests := []struct {
+		name           string
+		x              int
+		scaleX, scaleY float64
+		opts           SpriteOptions
+		w, h           int
+		want           []byte
+	}{
+		{"double", 0, 2, 2, SpriteOptions{}, 4, 4, []byte{
+			1, 1, 2, 2,
+			1, 1, 2, 2,
+			3, 3, 4, 4,
+			3, 3, 4, 4,
+		}},
+		// 2 * 1.5 = 3 pixels, stepping 2/3 of a source pixel in 16.16.
+		{"one and a half", 0, 1.5, 1, SpriteOptions{}, 3, 2, []byte{1, 1, 2, 3, 3, 4}},
+		{"flipped", 0, 1.5, 1, SpriteOptions{FlipX: true}, 3, 2, []byte{2, 2, 1, 4, 4, 3}},
+		{"halved", 0, 0.5, 0.5, SpriteOptions{}, 1, 1, []byte{1}},
+		{"too small to draw", 0, 0.1, 1, SpriteOptions{}, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.DrawSpriteZoom(tt.x, 0, s, tt.scaleX, tt.scaleY, tt.opts)
+			if got := region(fb, 0, 0, tt.w, tt.h); !slices.Equal(got, tt.want) {
+				t.Errorf("drew %v, want %v", got, tt.want)
+			}
+			if got := drawn(fb); got != len(tt.want) {
+				t.Errorf("drew %d pixels, want %d", got, len(tt.want))
+			}
+		})
+	}
+
+	// Clipped on the left, the source steps start part way across.
+	fb := NewFramebuffer(DefaultPalette())
+	fb.DrawSpriteZoom(-1, 0, s, 2, 1, SpriteOptions{})
+	if got, want := region(fb, 0, 0, 3, 1), []byte{1, 2, 2}; !slices.Equal(got, want) {
+		t.Errorf("clipped zoom drew %v, want %v", got, want)
+	}
+}
+
+func TestDrawSpriteRotozoom(t *testing.T) {
+	tests := []struct {
+		name         string
+		angle, scale float64
+		x, y, w, h   int // block to compare, centred on (50, 50)
+		want         []byte
+	}{
+		{"unrotated", 0, 1, 48, 49, 4, 2, []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		// A quarter turn clockwise on screen: the left column ends up on top.
+		{"quarter turn", math.Pi / 2, 1, 50, 48, 2, 4, []byte{
+			5, 1,
+			6, 2,
+			7, 3,
+			8, 4,
+		}},
+		{"half turn", math.Pi, 1, 49, 50, 4, 2, []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+		{"doubled", 0, 2, 46, 48, 8, 4, []byte{
+			1, 1, 2, 2, 3, 3, 4, 4,
+			1, 1, 2, 2, 3, 3, 4, 4,
+			5, 5, 6, 6, 7, 7, 8, 8,
+			5, 5, 6, 6, 7, 7, 8, 8,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fb := NewFramebuffer(DefaultPalette())
+			fb.DrawSpriteRotozoom(50, 50, testSprite(), tt.angle, tt.scale, SpriteOptions{})
+			if got := region(fb, tt.x, tt.y, tt.w, tt.h); !slices.Equal(got, tt.want) {
+				t.Errorf("drew %v, want %v", got, tt.want)
+			}
+			if got := drawn(fb); got != len(tt.want) {
+				t.Errorf("drew %d pixels, want %d", got, len(tt.want))
+			}
+		})
+	}
+
+	// Centred on the corner, only the bottom-right quarter shows.
+	fb := NewFramebuffer(DefaultPalette())
+	fb.DrawSpriteRotozoom(0, 0, testSprite(), 0, 1, SpriteOptions{})
+	if got, want := region(fb, 0, 0, 2, 1), []byte{7, 8}; !slices.Equal(got, want) || drawn(fb) != 2 {
+		t.Errorf("clipped rotozoom drew %v (%d pixels), want %v", got, drawn(fb), want)
+	}
+}
@@ -0,0 +1,109 @@
+package vga
+
+import "encoding/binary"
+
+// TextCell is one character cell of a text-mode screen. Attr follows the
+// VGA layout: low nibble is the foreground colour, high nibble the background.
+type TextCell struct {
+	Char byte
+	Attr byte
+}
+
+// TextScreen is a character buffer for text modes, the equivalent of the
+// B800h segment. DrawText rasterises it into a framebuffer.
+type TextScreen struct {
+	Cols  int
+	Rows  int
+	Cells []TextCell
+}
+
+// NewTextScreen creates a blank cols x rows text screen (light gray on black).
+func NewTextScreen(cols, rows int) *TextScreen {
+	t := &TextScreen{Cols: cols, Rows: rows, Cells: make([]TextCell, cols*rows)}
+	t.Clear(0x07)
+	return t
+}
+
+// Clear fills the screen with spaces in the given attribute.
+func (t *TextScreen) Clear(attr byte) {
+	for i := range t.Cells {
+		t.Cells[i] = TextCell{Char: ' ', Attr: attr}
+	}
+}
+
+// Put writes a character at column x, row y. Out-of-range cells are ignored.
+func (t *TextScreen) Put(x, y int, ch, attr byte) {
+	if x < 0 || x >= t.Cols || y < 0 || y >= t.Rows {
+		return
+	}
+	t.Cells[y*t.Cols+x] = TextCell{Char: ch, Attr: attr}
+}
+
+// Print writes a string starting at column x, row y, clipped to the row.
+func (t *TextScreen) Print(x, y int, s string, attr byte) {
+	for i := 0; i < len(s); i++ {
+		t.Put(x+i, y, s[i], attr)
+	}
+}
+
+// DrawText rasterises a text screen into the framebuffer using the 8x8 CP437
+// font, one cell per 8x8 pixel block starting at the top-left corner,
+// clipped to the current clip. Attribute colours are the standard 16, drawn
+// with the nearest entries of fb.Palette (entries 0-15 of DefaultPalette).
+func (fb *Framebuffer) DrawText(t *TextScreen) {
+	var colors [16]byte
+	for i, c := range standard16 {
+		colors[i] = fb.Palette.Nearest(c)
+	}
+	clip := fb.clip
+	for cy := 0; cy < t.Rows && cy*8 < clip.Max.Y; cy++ {
+		for cx := 0; cx < t.Cols && cx*8 < clip.Max.X; cx++ {
+			cell := t.Cells[cy*t.Cols+cx]
+			fg := colors[cell.Attr&0x0F]
+			bg := colors[cell.Attr>>4]
+			glyph := &CP437Font[cell.Char]
+			x0, x1 := max(cx*8, clip.Min.X), min(cx*8+8, clip.Max.X)
+			for y := max(cy*8, clip.Min.Y); y < min(cy*8+8, clip.Max.Y); y++ {
+				row := glyph[y-cy*8]
+				off := y * fb.Stride
+				for x := x0; x < x1; x++ {
+					if row&(1<<uint(x-cx*8)) != 0 {
+						fb.Pixels[off+x] = fg
+					} else {
+						fb.Pixels[off+x] = bg
+					}
+				}
+			}
+		}
+	}
+}
+
+// overlayText draws fb.Text over the converted RGBA frame in the standard 16
+// colours: the character layer of a text mode. Cells with attribute 0
+// (black on black) are transparent, so graphics drawn into Pixels show
+// through wherever no text has been written.
+func (fb *Framebuffer) overlayText() {
+	t := fb.Text
+	for cy := 0; cy < t.Rows && cy*8 < fb.Height; cy++ {
+		for cx := 0; cx < t.Cols && cx*8 < fb.Width; cx++ {
+			cell := t.Cells[cy*t.Cols+cx]
+			if cell.Attr == 0 {
+				continue
+			}
+			fg := packColor(standard16[cell.Attr&0x0F])
+			bg := packColor(standard16[cell.Attr>>4])
+			glyph := &CP437Font[cell.Char]
+			for gy := 0; gy < 8 && cy*8+gy < fb.Height; gy++ {
+				row := glyph[gy]
+				out := fb.rgba[((cy*8+gy)*fb.Width+cx*8)*4:]
+				for gx := 0; gx < 8 && cx*8+gx < fb.Width; gx++ {
+					c := bg
+					if row&(1<<uint(gx)) != 0 {
+						c = fg
+					}
+					binary.LittleEndian.PutUint32(out[gx*4:], c)
+				}
+			}
+		}
+	}
+}
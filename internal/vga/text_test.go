@@ -0,0 +1,48 @@
+package vga
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDrawText(t *testing.T) {
+	// Reversed, so the standard colours are not at indices 0-15.
+	var pal Palette
+	def := DefaultPalette()
+	for i := range pal {
+		pal[i] = def[255-i]
+	}
+	fb := NewFramebuffer(pal)
+	ts := NewTextScreen(40, 25)
+	ts.Clear(0x1E) // yellow on blue
+	ts.Put(0, 0, 1, 0x1E)
+	saved := CP437Font[1]
+	CP437Font[1] = [8]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF} // all foreground
+	defer func() { CP437Font[1] = saved }()
+
+	fb.PushClip(image.Rect(4, 4, 12, 12))
+	fb.DrawText(ts)
+	fg, bg := pal.Nearest(standard16[0x0E]), pal.Nearest(standard16[0x01])
+	if fg != 255-0x0E || bg != 255-0x01 {
+		t.Fatalf("standard colours at %d, %d", fg, bg)
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want byte
+	}{
+		{"outside the clip", 3, 4, 0},
+		{"block glyph", 4, 4, fg},
+		{"space", 8, 8, bg},
+		{"last clipped pixel", 11, 11, bg},
+		{"past the clip", 12, 11, 0},
+	}
+	for _, tt := range tests {
+		if got := fb.Pixels[tt.y*fb.Stride+tt.x]; got != tt.want {
+			t.Errorf("%s: (%d, %d) = %d, want %d", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+	if got := count(fb, fg) + count(fb, bg); got != 64 {
+		t.Errorf("drew %d pixels, want 64", got)
+	}
+}
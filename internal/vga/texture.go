@@ -0,0 +1,83 @@
+package vga
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+)
+
+// XORTexture returns a w x h sprite filled with the classic x^y pattern.
+func XORTexture(w, h int) *Sprite {
+	s := NewSprite(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			s.Pixels[y*w+x] = byte(x ^ y)
+		}
+	}
+	return s
+}
+
+// CheckerTexture returns a w x h checkerboard of cell-sized squares in
+// colours a and b.
+func CheckerTexture(w, h, cell int, a, b byte) *Sprite {
+	cell = max(cell, 1)
+	s := NewSprite(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x/cell+y/cell)&1 == 0 {
+				s.Pixels[y*w+x] = a
+			} else {
+				s.Pixels[y*w+x] = b
+			}
+		}
+	}
+	return s
+}
+
+// LoadSpritePNG loads a PNG as a sprite; see SpriteFromImage.
+func LoadSpritePNG(path string) (*Sprite, Palette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Palette{}, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, Palette{}, err
+	}
+	s, pal := SpriteFromImage(img)
+	return s, pal, nil
+}
+
+// SpriteFromImage converts img to a sprite and the palette to show it with.
+// Paletted images keep their indices and colours; anything else becomes a
+// 256-level greyscale by luminance, ready to be recoloured with another
+// palette.
+func SpriteFromImage(img image.Image) (*Sprite, Palette) {
+	b := img.Bounds()
+	s := NewSprite(b.Dx(), b.Dy())
+	if pi, ok := img.(*image.Paletted); ok {
+		var pal Palette
+		for i, c := range pi.Palette {
+			if i == len(pal) {
+				break
+			}
+			pal[i] = color.RGBAModel.Convert(c).(color.RGBA)
+			pal[i].A = 255
+		}
+		for y := 0; y < s.Height; y++ {
+			off := pi.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(s.Pixels[y*s.Width:(y+1)*s.Width], pi.Pix[off:])
+		}
+		return s, pal
+	}
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			g := color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray)
+			s.Pixels[y*s.Width+x] = g.Y
+		}
+	}
+	return s, GradientPalette(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255})
+}